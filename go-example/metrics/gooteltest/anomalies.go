@@ -0,0 +1,181 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	AnomalyLevelShift = "levelShift"
+	AnomalySpike      = "spike"
+	AnomalyDrift      = "drift"
+	AnomalyFlatline   = "flatline"
+)
+
+var anomalyTypes = map[string]bool{
+	AnomalyLevelShift: true,
+	AnomalySpike:      true,
+	AnomalyDrift:      true,
+	AnomalyFlatline:   true,
+}
+
+// Anomaly is a change to the values of a metric at a scheduled time.
+type Anomaly struct {
+
+	// The metric changed.
+	Metric string `yaml:"metric"`
+
+	// levelShift adds Amount to each value. spike adds Amount to each
+	// value too, but lasts one value set by default. drift adds an amount
+	// that grows linearly from 0 to Amount over Duration. flatline repeats
+	// the value from just before the anomaly.
+	Type string `yaml:"type"`
+
+	// When the anomaly starts, measured from the start of the run.
+	At time.Duration `yaml:"at"`
+
+	// How long the anomaly lasts. 0 means until the end of the run, except
+	// for spikes, which then last one value set. drift needs a duration.
+	Duration time.Duration `yaml:"duration,omitempty"`
+
+	// The amount added by levelShift, spike, and drift.
+	Amount float64 `yaml:"amount,omitempty"`
+}
+
+// AnomalyEvent records that an anomaly started or ended.
+type AnomalyEvent struct {
+	Anomaly Anomaly
+
+	// true if the anomaly started, false if it ended.
+	Start bool
+}
+
+// Anomalies applies anomalies to the samples of a run. Anomalies
+// instances are safe to use with multiple goroutines.
+type Anomalies struct {
+	anomalies []Anomaly
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// By index in anomalies.
+	active map[int]bool
+	done   map[int]bool
+	held   map[int]Sample
+
+	// The last sample before anomalies by metric, for flatlines.
+	last map[string]Sample
+}
+
+// NewAnomalies returns an Anomalies for anomalies.
+func NewAnomalies(anomalies []Anomaly) *Anomalies {
+	return &Anomalies{
+		anomalies: anomalies,
+		active:    make(map[int]bool),
+		done:      make(map[int]bool),
+		held:      make(map[int]Sample),
+		last:      make(map[string]Sample),
+	}
+}
+
+// Apply returns sample of metric with the anomalies in effect elapsed
+// after the start of the run applied. It also returns the anomalies of
+// metric that started or ended since the last call. An anomaly shorter
+// than a collect period may end before any sample falls inside it. Apply
+// then applies it to the next sample instead and ends it after that.
+func (a *Anomalies) Apply(
+	metric string,
+	elapsed time.Duration,
+	sample Sample) (Sample, []AnomalyEvent) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var events []AnomalyEvent
+	result := sample
+	for i, anomaly := range a.anomalies {
+		if anomaly.Metric != metric || a.done[i] {
+			continue
+		}
+		if elapsed < anomaly.At {
+			continue
+		}
+		ended := anomaly.Duration > 0 && elapsed >= anomaly.At+anomaly.Duration
+		if anomaly.Type == AnomalySpike && anomaly.Duration == 0 {
+			ended = a.active[i]
+		}
+		if ended && a.active[i] {
+			a.done[i] = true
+			events = append(events, AnomalyEvent{Anomaly: anomaly})
+			continue
+		}
+		if !a.active[i] {
+			a.active[i] = true
+			held, ok := a.last[metric]
+			if !ok {
+				held = sample
+			}
+			a.held[i] = held
+			events = append(events, AnomalyEvent{Anomaly: anomaly, Start: true})
+		}
+		switch anomaly.Type {
+		case AnomalyLevelShift, AnomalySpike:
+			result = result.shift(anomaly.Amount)
+		case AnomalyDrift:
+			fraction := math.Min(
+				float64(elapsed-anomaly.At)/float64(anomaly.Duration), 1)
+			result = result.shift(anomaly.Amount * fraction)
+		case AnomalyFlatline:
+			result = a.held[i]
+		}
+	}
+	a.last[metric] = sample
+	return result, events
+}
+
+// End ends the anomalies still in effect, as at the end of the run, and
+// returns their events.
+func (a *Anomalies) End() []AnomalyEvent {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var events []AnomalyEvent
+	for i, anomaly := range a.anomalies {
+		if a.active[i] && !a.done[i] {
+			a.done[i] = true
+			events = append(events, AnomalyEvent{Anomaly: anomaly})
+		}
+	}
+	return events
+}
+
+// shift returns s with amount added to its value and observations.
+func (s Sample) shift(amount float64) Sample {
+	observations := make([]float64, len(s.Observations))
+	for i, o := range s.Observations {
+		observations[i] = o + amount
+	}
+	s.Value += amount
+	s.Observations = observations
+	return s
+}
+
+func checkAnomalies(config *Config, metricTypes map[string]string) error {
+	for _, anomaly := range config.Anomalies {
+		if _, ok := metricTypes[anomaly.Metric]; !ok {
+			return fmt.Errorf(
+				"Unknown metric name '%s' in anomalies section", anomaly.Metric)
+		}
+		if !anomalyTypes[anomaly.Type] {
+			return fmt.Errorf("Unknown anomaly type: %s", anomaly.Type)
+		}
+		if anomaly.At < 0 || anomaly.Duration < 0 {
+			return errors.New("anomaly at and duration must not be negative")
+		}
+		if anomaly.Type == AnomalyDrift && anomaly.Duration == 0 {
+			return fmt.Errorf(
+				"Drift of metric '%s' needs a duration", anomaly.Metric)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package gooteltest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// anomalyStep is one call to Anomalies.Apply and what it should return.
+type anomalyStep struct {
+	elapsed time.Duration
+	value   float64
+	want    float64
+
+	// The anomalies that start or end, by index, as +i and -(i+1).
+	events []int
+}
+
+// playAnomalies applies anomalies to metric 'm' at each step.
+func playAnomalies(t *testing.T, anomalies []Anomaly, steps []anomalyStep) {
+	t.Helper()
+	a := NewAnomalies(anomalies)
+	for _, step := range steps {
+		got, events := a.Apply(
+			"m", step.elapsed, Sample{Value: step.value})
+		if got.Value != step.want {
+			t.Errorf("At %v: got %v, want %v",
+				step.elapsed, got.Value, step.want)
+		}
+		var want []AnomalyEvent
+		for _, e := range step.events {
+			if e >= 0 {
+				want = append(
+					want, AnomalyEvent{Anomaly: anomalies[e], Start: true})
+			} else {
+				want = append(want, AnomalyEvent{Anomaly: anomalies[-e-1]})
+			}
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("At %v: got events %+v, want %+v",
+				step.elapsed, events, want)
+		}
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	s := time.Second
+	for _, tc := range []struct {
+		name    string
+		anomaly Anomaly
+		steps   []anomalyStep
+	}{
+		{
+			name: "levelShift",
+			anomaly: Anomaly{
+				Type: AnomalyLevelShift, At: 2 * s, Duration: 2 * s, Amount: 10},
+			steps: []anomalyStep{
+				{elapsed: 0, value: 1, want: 1},
+				{elapsed: 2 * s, value: 1, want: 11, events: []int{0}},
+				{elapsed: 3 * s, value: 2, want: 12},
+				{elapsed: 4 * s, value: 2, want: 2, events: []int{-1}},
+			},
+		},
+		{
+			name:    "spike",
+			anomaly: Anomaly{Type: AnomalySpike, At: s, Amount: 100},
+			steps: []anomalyStep{
+				{elapsed: 0, value: 1, want: 1},
+				{elapsed: s, value: 1, want: 101, events: []int{0}},
+				{elapsed: 2 * s, value: 1, want: 1, events: []int{-1}},
+				{elapsed: 3 * s, value: 1, want: 1},
+			},
+		},
+		{
+			name: "drift",
+			anomaly: Anomaly{
+				Type: AnomalyDrift, At: s, Duration: 4 * s, Amount: 8},
+			steps: []anomalyStep{
+				{elapsed: s, value: 1, want: 1, events: []int{0}},
+				{elapsed: 2 * s, value: 1, want: 3},
+				{elapsed: 4 * s, value: 1, want: 7},
+				{elapsed: 5 * s, value: 1, want: 1, events: []int{-1}},
+			},
+		},
+		{
+			name: "flatline",
+			anomaly: Anomaly{
+				Type: AnomalyFlatline, At: 2 * s, Duration: 2 * s},
+			steps: []anomalyStep{
+				{elapsed: s, value: 5, want: 5},
+				{elapsed: 2 * s, value: 6, want: 5, events: []int{0}},
+				{elapsed: 3 * s, value: 7, want: 5},
+				{elapsed: 4 * s, value: 8, want: 8, events: []int{-1}},
+			},
+		},
+		{
+			// No sample falls inside the anomaly, so it applies to the
+			// next one in full.
+			name: "shorterThanCollectPeriod",
+			anomaly: Anomaly{
+				Type:     AnomalyDrift,
+				At:       1500 * time.Millisecond,
+				Duration: 100 * time.Millisecond,
+				Amount:   4,
+			},
+			steps: []anomalyStep{
+				{elapsed: s, value: 1, want: 1},
+				{elapsed: 2 * s, value: 1, want: 5, events: []int{0}},
+				{elapsed: 3 * s, value: 1, want: 1, events: []int{-1}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.anomaly.Metric = "m"
+			playAnomalies(t, []Anomaly{tc.anomaly}, tc.steps)
+		})
+	}
+}
+
+func TestAnomaliesOtherMetric(t *testing.T) {
+	a := NewAnomalies(
+		[]Anomaly{{Metric: "m", Type: AnomalyLevelShift, Amount: 1}})
+	got, events := a.Apply("n", time.Second, Sample{Value: 1})
+	if got.Value != 1 || events != nil {
+		t.Errorf("Got %v %v, want 1 and no events", got.Value, events)
+	}
+}
+
+func TestAnomaliesHistogram(t *testing.T) {
+	a := NewAnomalies(
+		[]Anomaly{{Metric: "m", Type: AnomalyLevelShift, Amount: 1}})
+	sample := Sample{Value: 1, Observations: []float64{1, 2}}
+	got, _ := a.Apply("m", 0, sample)
+	if want := []float64{2, 3}; !reflect.DeepEqual(got.Observations, want) {
+		t.Errorf("Got %v, want %v", got.Observations, want)
+	}
+	if sample.Observations[0] != 1 {
+		t.Error("Apply changed the sample passed in")
+	}
+}
+
+func TestAnomaliesEnd(t *testing.T) {
+	anomalies := []Anomaly{
+		{Metric: "m", Type: AnomalyLevelShift, At: 0, Amount: 1},
+		{
+			Metric:   "m",
+			Type:     AnomalyLevelShift,
+			At:       time.Second,
+			Duration: time.Second,
+		},
+		{Metric: "m", Type: AnomalyFlatline, At: time.Hour},
+	}
+	a := NewAnomalies(anomalies)
+	a.Apply("m", time.Second, Sample{})
+	a.Apply("m", 2*time.Second, Sample{})
+	// Only the first is still in effect: the second ended and the third
+	// never started.
+	want := []AnomalyEvent{{Anomaly: anomalies[0]}}
+	if got := a.End(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+	if got := a.End(); got != nil {
+		t.Errorf("Got %+v from second End, want none", got)
+	}
+}
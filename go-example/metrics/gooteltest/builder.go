@@ -0,0 +1,436 @@
+package gooteltest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SeriesID identifies one time series of a metric.
+type SeriesID struct {
+
+	// The resource attributes of the sender.
+	Resource map[string]string
+
+	// The metric name.
+	Metric string
+
+	// The attributes of the data points.
+	Attributes map[string]string
+}
+
+func (s SeriesID) key() string {
+	return fmt.Sprintf(
+		"%s\x00%s\x00%s",
+		attributesKey(s.Resource),
+		s.Metric,
+		attributesKey(s.Attributes))
+}
+
+func attributesKey(attrs map[string]string) string {
+	keys := sortedKeys(attrs)
+	for i, k := range keys {
+		keys[i] = k + "=" + attrs[k]
+	}
+	return strings.Join(keys, "\x01")
+}
+
+func sortedKeys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// MaxHistogramObservations is the most observations a ConfigBuilder
+// records for one histogram point.
+const MaxHistogramObservations = 10000
+
+// ConfigBuilder builds a Config from metric data points seen over time,
+// such as points recorded from a live OTLP stream. Points are grouped
+// into MetricValueSets by time: each MetricValueSet covers one collect
+// period starting at the time of the first point added.
+//
+// Cumulative sums and histograms are converted to deltas so that they
+// replay correctly. The first point of a cumulative series holds
+// everything counted before recording started, so it only sets the
+// baseline for the deltas that follow. Sums that are not monotonic become
+// gauges of their current level. Histograms are replayed by recording,
+// for each bucket, as many observations as the bucket count at the middle
+// of the bucket, so bucket counts are exact but sums are approximate. A
+// point with more than MaxHistogramObservations counts has its counts
+// scaled down to about that many.
+//
+// ConfigBuilder instances are not safe to use from multiple goroutines.
+type ConfigBuilder struct {
+	period time.Duration
+	start  time.Time
+	series map[string]*builderSeries
+
+	// The unit and description of each metric by name.
+	metadata map[string]builderMetadata
+}
+
+type builderMetadata struct {
+	unit        string
+	description string
+}
+
+// builderSeries is one recorded series.
+type builderSeries struct {
+	id          SeriesID
+	metricType  string
+	temporality string
+	boundaries  []float64
+
+	// MetricValues by collect period.
+	values map[int]*MetricValue
+
+	// The last cumulative value for converting cumulative to delta.
+	hasLast    bool
+	lastSum    float64
+	lastCounts []uint64
+}
+
+// NewConfigBuilder returns a new ConfigBuilder with the given collect
+// period.
+func NewConfigBuilder(period time.Duration) *ConfigBuilder {
+	return &ConfigBuilder{
+		period:   period,
+		series:   make(map[string]*builderSeries),
+		metadata: make(map[string]builderMetadata),
+	}
+}
+
+// SetMetadata sets the unit and description of the metric with the given
+// name. Units that CheckUnit rejects are left out of the Config.
+func (b *ConfigBuilder) SetMetadata(metric, unit, description string) {
+	if CheckUnit(unit) != nil {
+		unit = ""
+	}
+	b.metadata[metric] = builderMetadata{unit: unit, description: description}
+}
+
+// AddGauge adds a gauge data point.
+func (b *ConfigBuilder) AddGauge(id SeriesID, t time.Time, value float64) {
+	s := b.getSeries(id, MetricTypeGauge, "")
+	s.value(b.periodOf(t)).Value = value
+}
+
+// AddSum adds a sum data point. temporality is 'delta' or 'cumulative'.
+func (b *ConfigBuilder) AddSum(
+	id SeriesID,
+	t time.Time,
+	value float64,
+	temporality string,
+	monotonic bool) {
+	if !monotonic {
+		s := b.getSeries(id, MetricTypeGauge, "")
+		if temporality == DeltaAggregationSelector {
+			s.lastSum += value
+		} else {
+			s.lastSum = value
+		}
+		s.value(b.periodOf(t)).Value = s.lastSum
+		return
+	}
+	s := b.getSeries(id, MetricTypeSum, temporality)
+	delta := value
+	if temporality == CumulativeAggregationSelector {
+		switch {
+		case !s.hasLast:
+			delta = 0
+		case value >= s.lastSum:
+			delta = value - s.lastSum
+		}
+		s.hasLast = true
+		s.lastSum = value
+	}
+	s.value(b.periodOf(t)).Value += delta
+}
+
+// AddHistogram adds a histogram data point with explicit boundaries.
+// counts has one more element than boundaries. temporality is 'delta' or
+// 'cumulative'. Points whose boundaries differ from the first point of the
+// series are rejected.
+func (b *ConfigBuilder) AddHistogram(
+	id SeriesID,
+	t time.Time,
+	temporality string,
+	boundaries []float64,
+	counts []uint64) error {
+	if len(counts) != len(boundaries)+1 {
+		return fmt.Errorf(
+			"histogram '%s' has %d boundaries but %d bucket counts",
+			id.Metric,
+			len(boundaries),
+			len(counts))
+	}
+	s := b.getSeries(id, MetricTypeHistogram, temporality)
+	if s.boundaries == nil {
+		s.boundaries = append([]float64{}, boundaries...)
+	} else if !equalFloats(s.boundaries, boundaries) {
+		return fmt.Errorf("histogram '%s' changed boundaries", id.Metric)
+	}
+	deltas := counts
+	if temporality == CumulativeAggregationSelector {
+		deltas = make([]uint64, len(counts))
+		reset := false
+		for i := range counts {
+			if s.hasLast && counts[i] < s.lastCounts[i] {
+				reset = true
+			}
+		}
+		// The first point is the baseline, so its deltas stay 0.
+		for i := range counts {
+			switch {
+			case reset:
+				deltas[i] = counts[i]
+			case s.hasLast:
+				deltas[i] = counts[i] - s.lastCounts[i]
+			}
+		}
+		s.hasLast = true
+		s.lastCounts = append([]uint64{}, counts...)
+	}
+	v := s.value(b.periodOf(t))
+	if v.Observations == nil {
+		v.Observations = Observations{}
+	}
+	for i, count := range capCounts(deltas, MaxHistogramObservations) {
+		mid := bucketMidpoint(boundaries, i)
+		for j := uint64(0); j < count; j++ {
+			v.Observations = append(v.Observations, mid)
+		}
+	}
+	return nil
+}
+
+// capCounts returns counts scaled down so that their total is about max
+// or counts itself if the total is at most max. Buckets with a nonzero
+// count keep at least 1.
+func capCounts(counts []uint64, max uint64) []uint64 {
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total <= max {
+		return counts
+	}
+	result := make([]uint64, len(counts))
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		scaled := float64(count) / float64(total) * float64(max)
+		result[i] = uint64(math.Round(scaled))
+		if result[i] == 0 {
+			result[i] = 1
+		}
+	}
+	return result
+}
+
+// bucketMidpoint returns a value inside bucket i. The unbounded first and
+// last buckets get a value half a neighbouring bucket width beyond their
+// one boundary.
+func bucketMidpoint(boundaries []float64, i int) float64 {
+	n := len(boundaries)
+	switch {
+	case n == 0:
+		return 0
+	case n == 1 && i == 0:
+		return boundaries[0] - 1
+	case n == 1:
+		return boundaries[0] + 1
+	case i == 0:
+		return boundaries[0] - (boundaries[1]-boundaries[0])/2
+	case i == n:
+		return boundaries[n-1] + (boundaries[n-1]-boundaries[n-2])/2
+	default:
+		return (boundaries[i-1] + boundaries[i]) / 2
+	}
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *ConfigBuilder) periodOf(t time.Time) int {
+	if b.start.IsZero() {
+		b.start = t
+	}
+	if t.Before(b.start) {
+		return 0
+	}
+	return int(t.Sub(b.start) / b.period)
+}
+
+func (b *ConfigBuilder) getSeries(
+	id SeriesID, metricType, temporality string) *builderSeries {
+	key := id.key()
+	s, ok := b.series[key]
+	if !ok {
+		s = &builderSeries{
+			id:          id,
+			metricType:  metricType,
+			temporality: temporality,
+			values:      make(map[int]*MetricValue),
+		}
+		b.series[key] = s
+	}
+	return s
+}
+
+func (s *builderSeries) value(period int) *MetricValue {
+	v, ok := s.values[period]
+	if !ok {
+		v = &MetricValue{}
+		s.values[period] = v
+	}
+	return v
+}
+
+// Config returns the Config for everything added so far. If all points
+// came from the same resource, that resource becomes the one service in
+// the Config. Otherwise the resource attributes of each series are added
+// to its attributes.
+func (b *ConfigBuilder) Config() (*Config, error) {
+	var series []*builderSeries
+	resources := make(map[string]map[string]string)
+	for _, s := range b.series {
+		series = append(series, s)
+		resources[attributesKey(s.id.Resource)] = s.id.Resource
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].id.Metric != series[j].id.Metric {
+			return series[i].id.Metric < series[j].id.Metric
+		}
+		return series[i].id.key() < series[j].id.key()
+	})
+
+	noPrefix := ""
+	result := &Config{
+		CollectPeriod: b.period,
+		NamePrefix:    &noPrefix,
+	}
+	mergeResource := len(resources) > 1
+	if len(resources) == 1 {
+		for _, res := range resources {
+			if service, ok := serviceFromResource(res); ok {
+				result.Services = append(result.Services, service)
+			}
+		}
+	}
+
+	seriesPerMetric := make(map[string]int)
+	for _, s := range series {
+		seriesPerMetric[s.id.Metric]++
+	}
+	indexes := make(map[string]int)
+	maxPeriod := -1
+	names := make([]string, len(series))
+	for i, s := range series {
+		info := MetricInfo{
+			Name:        s.id.Metric,
+			Type:        s.metricType,
+			Temporality: s.temporality,
+			Attributes:  copyAttributes(s.id.Attributes),
+			Boundaries:  s.boundaries,
+			Unit:        b.metadata[s.id.Metric].unit,
+			Description: b.metadata[s.id.Metric].description,
+		}
+		if mergeResource {
+			for k, v := range s.id.Resource {
+				if info.Attributes == nil {
+					info.Attributes = make(map[string]string)
+				}
+				info.Attributes[k] = v
+			}
+		}
+		if seriesPerMetric[s.id.Metric] > 1 {
+			indexes[s.id.Metric]++
+			info.Instrument = info.Name
+			info.Name = fmt.Sprintf("%s#%d", info.Name, indexes[s.id.Metric])
+		}
+		names[i] = info.Name
+		result.Metrics = append(result.Metrics, info)
+		for period := range s.values {
+			if period > maxPeriod {
+				maxPeriod = period
+			}
+		}
+	}
+
+	for period := 0; period <= maxPeriod; period++ {
+		var valueSet MetricValueSet
+		for i, s := range series {
+			v, ok := s.values[period]
+			if !ok {
+				// A gauge keeps its last value, but a sum or histogram
+				// with no data must record nothing.
+				switch s.metricType {
+				case MetricTypeGauge:
+					continue
+				case MetricTypeSum:
+					v = &MetricValue{}
+				case MetricTypeHistogram:
+					v = &MetricValue{Observations: Observations{}}
+				}
+			}
+			metricValue := *v
+			metricValue.Name = names[i]
+			valueSet.ValueSet = append(valueSet.ValueSet, metricValue)
+		}
+		result.ValueSets = append(result.ValueSets, valueSet)
+	}
+
+	result.fixDefaults()
+	if err := checkConfig(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// serviceFromResource returns the ServiceInfo for a resource with a
+// service.name attribute.
+func serviceFromResource(res map[string]string) (ServiceInfo, bool) {
+	name, ok := res["service.name"]
+	if !ok {
+		return ServiceInfo{}, false
+	}
+	result := ServiceInfo{Name: name, Replicas: 1}
+	for k, v := range res {
+		if k == "service.name" || k == "service.instance.id" {
+			continue
+		}
+		if result.Attributes == nil {
+			result.Attributes = make(map[string]string)
+		}
+		result.Attributes[k] = v
+	}
+	return result, true
+}
+
+func copyAttributes(attrs map[string]string) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		result[k] = v
+	}
+	return result
+}
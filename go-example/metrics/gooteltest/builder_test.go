@@ -0,0 +1,102 @@
+package gooteltest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddHistogramCumulative(t *testing.T) {
+	b := NewConfigBuilder(time.Minute)
+	id := SeriesID{Metric: "latency"}
+	start := time.Unix(1700000000, 0)
+	boundaries := []float64{1, 2}
+	for i, counts := range [][]uint64{
+		{10000000, 5000000, 0},
+		{10000002, 5000003, 1},
+		// A reset: the counts start again from 0.
+		{1, 0, 0},
+	} {
+		err := b.AddHistogram(
+			id,
+			start.Add(time.Duration(i)*time.Minute),
+			CumulativeAggregationSelector,
+			boundaries,
+			counts)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := b.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Observations{
+		{},
+		{0.5, 0.5, 1.5, 1.5, 1.5, 2.5},
+		{0.5},
+	}
+	if len(config.ValueSets) != len(want) {
+		t.Fatalf("Got %d value sets, want %d", len(config.ValueSets), len(want))
+	}
+	for i, observations := range want {
+		got := config.ValueSets[i].ValueSet[0].Observations
+		if !reflect.DeepEqual(got, observations) {
+			t.Errorf("Value set %d: got %v, want %v", i, got, observations)
+		}
+	}
+}
+
+func TestAddHistogramCapsObservations(t *testing.T) {
+	b := NewConfigBuilder(time.Minute)
+	err := b.AddHistogram(
+		SeriesID{Metric: "latency"},
+		time.Unix(1700000000, 0),
+		DeltaAggregationSelector,
+		[]float64{1, 2},
+		[]uint64{30000000, 1, 10000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := b.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[float64]int)
+	for _, x := range config.ValueSets[0].ValueSet[0].Observations {
+		counts[x]++
+	}
+	want := map[float64]int{
+		0.5: MaxHistogramObservations * 3 / 4,
+		1.5: 1,
+		2.5: MaxHistogramObservations / 4,
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("Got %v, want %v", counts, want)
+	}
+}
+
+func TestAddSumCumulative(t *testing.T) {
+	b := NewConfigBuilder(time.Minute)
+	id := SeriesID{Metric: "requests"}
+	start := time.Unix(1700000000, 0)
+	for i, value := range []float64{5000, 5007, 5010, 2} {
+		b.AddSum(
+			id,
+			start.Add(time.Duration(i)*time.Minute),
+			value,
+			CumulativeAggregationSelector,
+			true)
+	}
+	config, err := b.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	for _, valueSet := range config.ValueSets {
+		got = append(got, valueSet.ValueSet[0].Value)
+	}
+	if want := []float64{0, 7, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+)
+
+// anomalyEntry is one line of the anomaly log.
+type anomalyEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Event     string    `json:"event"`
+	Metric    string    `json:"metric"`
+	Type      string    `json:"type"`
+	At        string    `json:"at"`
+	Duration  string    `json:"duration,omitempty"`
+	Amount    float64   `json:"amount,omitempty"`
+}
+
+// anomalyLog writes a JSON line each time an anomaly starts or ends so
+// that alerts can be checked against it. A nil anomalyLog writes nothing.
+// anomalyLog is only used from the play goroutine.
+type anomalyLog struct {
+	f       *os.File
+	encoder *json.Encoder
+}
+
+// newAnomalyLog creates the anomaly log at path. It returns nil if path is
+// empty.
+func newAnomalyLog(path string) (*anomalyLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &anomalyLog{f: f, encoder: json.NewEncoder(f)}, nil
+}
+
+func (l *anomalyLog) write(events []gooteltest.AnomalyEvent) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	for _, e := range events {
+		entry := anomalyEntry{
+			Timestamp: now,
+			Event:     "end",
+			Metric:    e.Anomaly.Metric,
+			Type:      e.Anomaly.Type,
+			At:        e.Anomaly.At.String(),
+			Amount:    e.Anomaly.Amount,
+		}
+		if e.Start {
+			entry.Event = "start"
+		}
+		if e.Anomaly.Duration > 0 {
+			entry.Duration = e.Anomaly.Duration.String()
+		}
+		reportErr(l.encoder.Encode(entry), "failed to write anomaly log")
+	}
+}
+
+func (l *anomalyLog) close() {
+	if l == nil {
+		return
+	}
+	reportErr(l.f.Close(), "failed to close anomaly log")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exemplarContext returns the context to record sample in. If sample has
+// an exemplar, or if the config asks for generated exemplars, the context
+// carries a sampled span context so that the SDK can attach an exemplar
+// to the recorded value. This relies on the stable metrics SDK. The v0.30
+// SDK drops exemplars.
+func exemplarContext(
+	config *gooteltest.Config, sample gooteltest.Sample) context.Context {
+	ctx := context.Background()
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	switch {
+	case sample.Exemplar != nil:
+		// checkConfig already verified the IDs.
+		tid, _ := hex.DecodeString(sample.Exemplar.TraceID)
+		sid, _ := hex.DecodeString(sample.Exemplar.SpanID)
+		copy(traceID[:], tid)
+		copy(spanID[:], sid)
+	case config.GenerateExemplars:
+		_, _ = rand.Read(traceID[:])
+		_, _ = rand.Read(spanID[:])
+	default:
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(
+		trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		}))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRecordExemplars(t *testing.T) {
+	config := readConfig(t, `
+namePrefix: ""
+metrics:
+- name: "requests"
+  type: "sum"
+- name: "latency"
+  type: "histogram"
+- name: "errors"
+  type: "sum"
+valueSets:
+- valueSet:
+  - name: "requests"
+    value: 3
+    exemplar:
+      traceId: "4bf92f3577b34da6a3ce929d0e0e4736"
+      spanId: "00f067aa0ba902b7"
+  - name: "latency"
+    value: 1.5
+    exemplar:
+      traceId: "0af7651916cd43dd8448eb211c80319c"
+      spanId: "b7ad6b7169203331"
+  - name: "errors"
+    value: 1
+`)
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	got := collect(t, reader)
+
+	type exemplar struct {
+		traceID, spanID string
+		value           float64
+	}
+	exemplars := func(name string) []exemplar {
+		var result []exemplar
+		var all []metricdata.Exemplar[float64]
+		switch data := got[name].Data.(type) {
+		case metricdata.Sum[float64]:
+			for _, point := range data.DataPoints {
+				all = append(all, point.Exemplars...)
+			}
+		case metricdata.Histogram[float64]:
+			for _, point := range data.DataPoints {
+				all = append(all, point.Exemplars...)
+			}
+		}
+		for _, e := range all {
+			result = append(result, exemplar{
+				traceID: hex.EncodeToString(e.TraceID),
+				spanID:  hex.EncodeToString(e.SpanID),
+				value:   e.Value,
+			})
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		metric string
+		want   []exemplar
+	}{
+		{
+			metric: "requests",
+			want: []exemplar{{
+				traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				spanID:  "00f067aa0ba902b7",
+				value:   3,
+			}},
+		},
+		{
+			metric: "latency",
+			want: []exemplar{{
+				traceID: "0af7651916cd43dd8448eb211c80319c",
+				spanID:  "b7ad6b7169203331",
+				value:   1.5,
+			}},
+		},
+		// Without an exemplar, the value is not recorded in a sampled
+		// span context, so the SDK keeps none.
+		{metric: "errors"},
+	} {
+		if got := exemplars(tc.metric); len(got) != len(tc.want) ||
+			(len(got) > 0 && got[0] != tc.want[0]) {
+			t.Errorf("Exemplars of %s: got %+v, want %+v",
+				tc.metric, got, tc.want)
+		}
+	}
+}
+
+func TestGenerateExemplars(t *testing.T) {
+	config := readConfig(t, `
+namePrefix: ""
+generateExemplars: true
+metrics:
+- name: "requests"
+  type: "sum"
+valueSets:
+- valueSet:
+  - name: "requests"
+    value: 3
+`)
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	sum := collect(t, reader)["requests"].Data.(metricdata.Sum[float64])
+	exemplars := sum.DataPoints[0].Exemplars
+	if len(exemplars) != 1 {
+		t.Fatalf("Got %d exemplars, want 1", len(exemplars))
+	}
+	if len(exemplars[0].TraceID) != 16 || len(exemplars[0].SpanID) != 8 {
+		t.Errorf("Exemplar has trace ID %x and span ID %x",
+			exemplars[0].TraceID, exemplars[0].SpanID)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+)
+
+// lintMain runs 'oteltester lint' which reports what Wavefront would
+// sanitize, truncate, or drop in a config or in a file of OTLP metrics.
+// It exits with status 1 if it finds anything.
+func lintMain(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: oteltester lint [flags] [-config file | -otlp file]")
+		flags.PrintDefaults()
+	}
+	var configPath configPaths
+	flags.Var(
+		&configPath,
+		"config",
+		"Config file or directory to lint. May be given more than once to merge several")
+	otlpPath := flags.String(
+		"otlp", "", "OTLP JSON or protobuf file written by the collector's file exporter to lint")
+	params := gooteltest.Params{}
+	flags.Var(
+		params,
+		"set",
+		"Set a config placeholder as name=value. May be given more than once")
+	_ = flags.Parse(args)
+	if (len(configPath) == 0) == (*otlpPath == "") {
+		fmt.Println("Need to specify either -config or -otlp.")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var issues []gooteltest.LintIssue
+	if len(configPath) > 0 {
+		config, err := gooteltest.ReadConfigFromFilesWithParams(params, configPath...)
+		if err != nil {
+			log.Fatalf("Error opening config file: %v", err)
+		}
+		issues = gooteltest.LintWavefront(config)
+	} else {
+		issues = lintOTLPFile(*otlpPath)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+func lintOTLPFile(path string) []gooteltest.LintIssue {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", path, err)
+	}
+	defer f.Close()
+	reader, err := gooteltest.NewOTLPReader(f, gooteltest.SignalMetrics)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", path, err)
+	}
+	var result []gooteltest.LintIssue
+	seen := make(map[string]bool)
+	for {
+		batch, err := reader.Next()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", path, err)
+		}
+		if batch.Metrics == nil {
+			continue
+		}
+		for _, issue := range gooteltest.LintWavefrontOTLP(
+			batch.Metrics.GetResourceMetrics()) {
+			if !seen[issue.String()] {
+				seen[issue.String()] = true
+				result = append(result, issue)
+			}
+		}
+	}
+}
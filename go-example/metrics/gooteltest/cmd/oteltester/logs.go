@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+)
+
+// logSender sends the log streams in the config over OTLP. The logs SDK
+// and exporters of the Go SDK are still experimental, versioned apart
+// from the v1.32 metrics and traces modules this module uses, so it
+// builds the OTLP requests itself. Records are batched and sent once per
+// collect period.
+type logSender struct {
+	config *gooteltest.Config
+	conn   *grpc.ClientConn
+	client collogspb.LogsServiceClient
+
+	// traces is used for trace correlation. It may be nil.
+	traces *traceSender
+
+	// resources by service name. The empty name is the default service.
+	resources map[string][]*resourcepb.Resource
+
+	templates map[string]*template.Template
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// The records waiting to be sent by resource.
+	pending map[*resourcepb.Resource][]*logspb.LogRecord
+}
+
+// initLogs starts the connection with the OTEL collector for the logs in
+// config.
+func initLogs(
+	ctx context.Context,
+	config *gooteltest.Config,
+	traces *traceSender) (*logSender, error) {
+	conn, err := dialOTLP(ctx, "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	if err != nil {
+		return nil, err
+	}
+	result := &logSender{
+		config:    config,
+		conn:      conn,
+		client:    collogspb.NewLogsServiceClient(conn),
+		traces:    traces,
+		resources: make(map[string][]*resourcepb.Resource),
+		templates: make(map[string]*template.Template),
+		pending:   make(map[*resourcepb.Resource][]*logspb.LogRecord),
+	}
+	for _, stream := range config.Logs {
+		// checkConfig already verified the template.
+		result.templates[stream.Name], _ = stream.BodyTemplate()
+		if _, ok := result.resources[stream.Service]; ok {
+			continue
+		}
+		resources, err := serviceResources(ctx, config, stream.Service)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range resources {
+			result.resources[stream.Service] = append(
+				result.resources[stream.Service], toProtoResource(res))
+		}
+	}
+	return result, nil
+}
+
+// run sends each stream at its rate until ctx is done. It sends the last
+// batch before returning.
+func (l *logSender) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, stream := range l.config.Logs {
+		wg.Add(1)
+		go func(stream gooteltest.LogStream) {
+			defer wg.Done()
+			ticker := time.NewTicker(
+				time.Duration(float64(time.Second) / stream.Rate))
+			defer ticker.Stop()
+			for count := 0; ; count++ {
+				l.add(stream, count)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(stream)
+	}
+	ticker := time.NewTicker(l.config.CollectPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			l.flush()
+			return
+		case <-ticker.C:
+			l.flush()
+		}
+	}
+}
+
+// shutdown closes the connection to the OTEL collector.
+func (l *logSender) shutdown() {
+	reportErr(l.conn.Close(), "failed to close logs connection")
+}
+
+// add queues one record of stream. count is the number of records of the
+// stream sent so far. It picks which replica sends the record.
+func (l *logSender) add(stream gooteltest.LogStream, count int) {
+	now := time.Now()
+	resources := l.resources[stream.Service]
+	res := resources[count%len(resources)]
+	severity, _ := gooteltest.SeverityNumber(stream.Severity)
+	var body bytes.Buffer
+	err := l.templates[stream.Name].Execute(&body, gooteltest.LogBodyData{
+		Stream:  stream.Name,
+		Service: stream.Service,
+		Count:   count,
+		Time:    now.Format(time.RFC3339Nano),
+	})
+	reportErr(err, "failed to execute log body template")
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       logspb.SeverityNumber(severity),
+		SeverityText:         strings.ToUpper(stream.Severity),
+		Body:                 stringValue(body.String()),
+		Attributes:           toProtoAttributes(toAttributes(stream.Attributes)),
+	}
+	if stream.Trace != "" && l.traces != nil {
+		if sc, ok := l.traces.lastSpan(stream.Trace, stream.Span); ok {
+			traceID := sc.TraceID()
+			spanID := sc.SpanID()
+			record.TraceId = traceID[:]
+			record.SpanId = spanID[:]
+			record.Flags = uint32(sc.TraceFlags())
+		}
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.pending[res] = append(l.pending[res], record)
+}
+
+// flush sends all queued records.
+func (l *logSender) flush() {
+	l.lock.Lock()
+	pending := l.pending
+	l.pending = make(map[*resourcepb.Resource][]*logspb.LogRecord)
+	l.lock.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	request := &collogspb.ExportLogsServiceRequest{}
+	for res, records := range pending {
+		request.ResourceLogs = append(request.ResourceLogs, &logspb.ResourceLogs{
+			Resource: res,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: "oteltester"},
+				LogRecords: records,
+			}},
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := l.client.Export(ctx, request)
+	reportErr(err, "failed to export logs")
+}
+
+func toProtoResource(res *resource.Resource) *resourcepb.Resource {
+	return &resourcepb.Resource{
+		Attributes: toProtoAttributes(res.Attributes()),
+	}
+}
+
+func toProtoAttributes(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	result := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		value := &commonpb.AnyValue{}
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			value.Value = &commonpb.AnyValue_BoolValue{BoolValue: kv.Value.AsBool()}
+		case attribute.INT64:
+			value.Value = &commonpb.AnyValue_IntValue{IntValue: kv.Value.AsInt64()}
+		case attribute.FLOAT64:
+			value.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: kv.Value.AsFloat64()}
+		default:
+			value = stringValue(kv.Value.Emit())
+		}
+		result = append(result, &commonpb.KeyValue{Key: string(kv.Key), Value: value})
+	}
+	return result
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{
+		Value: &commonpb.AnyValue_StringValue{StringValue: s},
+	}
+}
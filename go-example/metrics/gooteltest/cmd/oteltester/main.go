@@ -2,83 +2,112 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/metric"
-	"go.opentelemetry.io/otel/metric/global"
-	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
-	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
-	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
-	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
-	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 )
 
 var (
-	fConfig string
+	fConfig          configPaths
+	fParams          = gooteltest.Params{}
+	fReport          string
+	fReportFormat    string
+	fDuration        time.Duration
+	fIterations      int
+	fPointsPerSecond float64
+	fAnomalyLog      string
+	fState           string
 )
 
 // initMetric starts the connection with the OTEL collector and returns a
-// no-arg function that can be called to shut down the connection. It also
-// registers a global meter provider which is used to register metrics to
-// be sent to the OTEL collector.
-func initMetric(config *gooteltest.Config) func() {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	temporalitySelector := aggregation.CumulativeTemporalitySelector()
-	if config.AggregationTemporalitySelector == gooteltest.DeltaAggregationSelector {
-		temporalitySelector = aggregation.DeltaTemporalitySelector()
-	}
+// meter provider which is used to register metrics to be sent to the OTEL
+// collector along with a no-arg function that can be called to shut down
+// the connection. If report is non-nil, everything exported is recorded
+// in it.
+func initMetric(
+	ctx context.Context,
+	config *gooteltest.Config,
+	res *resource.Resource,
+	report *runReport) (metric.MeterProvider, func()) {
 	// Wrap the raw grpc connection to OTEL collector with an exporter.
-	metricExporter, err := newExporter(ctx, temporalitySelector)
-	reportErr(err, "failed to create metric exporter")
-
-	res, err := newResource(ctx)
-	reportErr(err, "failed to create res")
-
-	// Create a contoller with the exporter which will be the global meter
-	// provider. Note that the explicit boundaries of all histograms must be
-	// set globally here.
-	cont := controller.New(
-		processor.NewFactory(
-			simple.NewWithHistogramDistribution(
-				histogram.WithExplicitBoundaries([]float64{1.0, 2.0, 5.0, 10.0}),
-			),
-			temporalitySelector,
-		),
-		controller.WithResource(res),
-		controller.WithExporter(metricExporter),
-		controller.WithCollectPeriod(config.CollectPeriod),
-	)
-
-	// Start the controller
-	reportErr(cont.Start(context.Background()), "failed to start controller")
+	otlpExporter, err := otlpmetricgrpc.New(
+		ctx,
+		otlpmetricgrpc.WithTemporalitySelector(temporalitySelector(
+			toTemporality(config.AggregationTemporalitySelector))))
+	if err != nil {
+		log.Fatalf("failed to create metric exporter: %v", err)
+	}
+	var metricExporter sdkmetric.Exporter = otlpExporter
+	if report != nil {
+		metricExporter = &reportingExporter{
+			Exporter: metricExporter, report: report}
+	}
 
-	// Register controller as global meter provider.
-	global.SetMeterProvider(cont)
+	provider := newMeterProvider(config, res, metricExporter)
 
-	// Our quit function that we return will stop the controller and cancel
-	// the context on the exporter.
-	return func() {
-		_ = cont.Stop(context.Background())
-		cancel()
+	// Our quit function that we return will do a final export and then
+	// close the connection. Shutdown cancels an export in progress, but
+	// a flush waits for it, so flush first.
+	return provider, func() {
+		reportErr(
+			provider.ForceFlush(context.Background()),
+			"failed to flush meter provider")
+		reportErr(
+			provider.Shutdown(context.Background()),
+			"failed to shut down meter provider")
 	}
 }
 
-func newExporter(ctx context.Context, temporalitySelector aggregation.TemporalitySelector) (*otlpmetric.Exporter, error) {
-	return otlpmetric.New(
-		ctx,
-		otlpmetricgrpc.NewClient(),
-		otlpmetric.WithMetricAggregationTemporalitySelector(temporalitySelector))
+// newMeterProvider returns a meter provider for config that sends what it
+// collects to exporter every export period of config.
+func newMeterProvider(
+	config *gooteltest.Config,
+	res *resource.Resource,
+	exporter sdkmetric.Exporter) *sdkmetric.MeterProvider {
+	// Metrics whose temporality is not the default come from a manual
+	// reader with the other temporality.
+	var manualReader *sdkmetric.ManualReader
+	if names := otherTemporalityNames(config); len(names) > 0 {
+		other := metricdata.DeltaTemporality
+		if toTemporality(config.AggregationTemporalitySelector) ==
+			metricdata.DeltaTemporality {
+			other = metricdata.CumulativeTemporality
+		}
+		manualReader = sdkmetric.NewManualReader(
+			sdkmetric.WithTemporalitySelector(temporalitySelector(other)))
+		exporter = &mixedTemporalityExporter{
+			Exporter: exporter, reader: manualReader, names: names}
+	}
+
+	// The periodic reader must come first: the provider shuts readers
+	// down in order, and the final export of the periodic reader collects
+	// from the manual reader.
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithView(newView(config)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(
+			exporter,
+			sdkmetric.WithInterval(config.ExportPeriod()))),
+	}
+	if manualReader != nil {
+		opts = append(opts, sdkmetric.WithReader(manualReader))
+	}
+	return sdkmetric.NewMeterProvider(opts...)
 }
 
 func reportErr(err error, message string) {
@@ -87,7 +116,26 @@ func reportErr(err error, message string) {
 	}
 }
 
-func newResource(ctx context.Context) (*resource.Resource, error) {
+// newResources returns one resource for each simulated service instance.
+// If config has no services, it returns a single default resource.
+func newResources(
+	ctx context.Context,
+	config *gooteltest.Config) ([]*resource.Resource, error) {
+	if len(config.Services) == 0 {
+		return serviceResources(ctx, config, "")
+	}
+	var result []*resource.Resource
+	for _, service := range config.Services {
+		resources, err := serviceResources(ctx, config, service.Name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resources...)
+	}
+	return result, nil
+}
+
+func newDefaultResource(ctx context.Context) (*resource.Resource, error) {
 	return resource.New(ctx,
 		resource.WithAttributes(
 			// the service name used to display traces in backends
@@ -97,92 +145,569 @@ func newResource(ctx context.Context) (*resource.Resource, error) {
 	)
 }
 
+// serviceResources returns the resources for the service with the given
+// name. A service in config has one resource per replica, and any other
+// service has a single resource. The empty name means the default
+// service.
+func serviceResources(
+	ctx context.Context,
+	config *gooteltest.Config,
+	name string) ([]*resource.Resource, error) {
+	if name == "" {
+		res, err := newDefaultResource(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*resource.Resource{res}, nil
+	}
+	service := gooteltest.ServiceInfo{Name: name, Replicas: 1}
+	for _, s := range config.Services {
+		if s.Name == name {
+			service = s
+		}
+	}
+	var result []*resource.Resource
+	for i := 0; i < service.Replicas; i++ {
+		res, err := newServiceResource(ctx, service, i)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, res)
+	}
+	return result, nil
+}
+
+// newServiceResource returns the resource for the given replica of
+// service.
+func newServiceResource(
+	ctx context.Context,
+	service gooteltest.ServiceInfo,
+	replica int) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(service.Name),
+		semconv.ServiceInstanceIDKey.String(
+			fmt.Sprintf("%s-%d", service.Name, replica)),
+	}
+	for k, v := range service.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return resource.New(ctx, resource.WithAttributes(attrs...))
+}
+
 func registerGaugeMetric(
+	ctx context.Context,
 	meter metric.Meter,
 	name string,
-	engine *gooteltest.Engine,
+	opts []metric.InstrumentOption,
+	value float64,
+	attrs ...attribute.KeyValue,
 ) {
 
-	gaugeObserver, err := meter.AsyncFloat64().Gauge(name)
+	gauge, err := meter.Float64Gauge(
+		name, instrumentOptions[metric.Float64GaugeOption](opts)...)
 	if err != nil {
-		log.Fatalf("failed to initialize instrument: %v", err)
+		instrumentErr(name, err)
 	}
-	gaugeObserver.Observe(context.Background(), engine.NextValue(name))
+	gauge.Record(ctx, value, metric.WithAttributes(attrs...))
 }
 
 func registerSumMetric(
+	ctx context.Context,
 	meter metric.Meter,
 	name string,
-	prefix string,
-	engine *gooteltest.Engine,
+	opts []metric.InstrumentOption,
+	value float64,
+	attrs ...attribute.KeyValue,
 ) {
-	counter, err := meter.SyncFloat64().Counter(prefix + name)
+	counter, err := meter.Float64Counter(
+		name, instrumentOptions[metric.Float64CounterOption](opts)...)
 	if err != nil {
-		log.Fatalf("failed to initialize instrument: %v", err)
+		instrumentErr(name, err)
 	}
 
-	counter.Add(context.Background(), engine.NextValue(name))
+	counter.Add(ctx, value, metric.WithAttributes(attrs...))
 }
 
 func registerHistograms(
+	ctx context.Context,
 	meter metric.Meter,
 	name string,
-	prefix string,
-	engine *gooteltest.Engine,
+	opts []metric.InstrumentOption,
+	observations []float64,
+	attrs ...attribute.KeyValue,
 ) {
-	histogram, err := meter.SyncFloat64().Histogram(prefix + name)
+	histogram, err := meter.Float64Histogram(
+		name, instrumentOptions[metric.Float64HistogramOption](opts)...)
 	if err != nil {
+		instrumentErr(name, err)
+	}
+
+	recordOpts := metric.WithAttributes(attrs...)
+	for _, value := range observations {
+		histogram.Record(ctx, value, recordOpts)
+	}
+}
+
+// loggedInstrumentErrs holds the names of the instruments whose errors
+// have been logged.
+var loggedInstrumentErrs sync.Map
+
+// instrumentErr handles err, the error of creating the instrument with the
+// given name. The SDK rejects names that are too long or have characters
+// such as ':', which faults and Prometheus imports produce, but still
+// returns an instrument that works, so these errors are logged once per
+// name and recording goes on. Any other error is fatal.
+func instrumentErr(name string, err error) {
+	if !errors.Is(err, sdkmetric.ErrInstrumentName) {
 		log.Fatalf("failed to initialize instrument: %v", err)
 	}
+	if _, logged := loggedInstrumentErrs.LoadOrStore(name, true); !logged {
+		log.Printf("%v, recording anyway", err)
+	}
+}
 
-	histogram.Record(context.Background(), engine.NextValue(name))
+// instrumentOptions returns opts as options of the instrument type that
+// takes options of type T.
+func instrumentOptions[T any](opts []metric.InstrumentOption) []T {
+	result := make([]T, len(opts))
+	for i, opt := range opts {
+		result[i] = opt.(T)
+	}
+	return result
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		recordMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replayMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-prometheus" {
+		importPrometheusMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		lintMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		renderMain(os.Args[2:])
+		return
+	}
 	flag.Parse()
-	if fConfig == "" {
+	if len(fConfig) == 0 {
 		fmt.Println("Need to specify -config flag.")
 		flag.Usage()
 		os.Exit(1)
 	}
-	config, err := gooteltest.ReadConfigFromFile(fConfig)
+	config, err := gooteltest.ReadConfigFromFilesWithParams(fParams, fConfig...)
 	if err != nil {
 		log.Fatalf("Error opening config file: %v", err)
 	}
 
-	prefix := "cum_"
-	if config.AggregationTemporalitySelector == gooteltest.DeltaAggregationSelector {
-		prefix = "delta_"
+	hash, err := config.Hash()
+	if err != nil {
+		log.Fatalf("Error hashing config: %v", err)
+	}
+	var report *runReport
+	if fReport != "" {
+		report = newRunReport(hash)
+	}
+	var resume *playState
+	if fState != "" {
+		resume, err = readPlayState(fState, hash)
+		if err != nil {
+			log.Fatalf("Error reading state: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	resources, err := newResources(ctx, config)
+	if err != nil {
+		log.Fatalf("Error creating resources: %v", err)
+	}
+	var meters []metric.Meter
+	var shutdowns []func()
+	for _, res := range resources {
+		provider, shutdown := initMetric(ctx, config, res, report)
+		meters = append(meters, provider.Meter(config.Meter))
+		shutdowns = append(shutdowns, shutdown)
 	}
 
-	shutdown := initMetric(config)
-	defer shutdown()
-	engine := gooteltest.NewEngine(config.ValueSets)
-	meter := global.Meter("opamp")
+	if fDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fDuration)
+		defer cancel()
+	}
 
-	go forever(meter, config, engine, prefix)
-	select {} // block forever
+	var traces *traceSender
+	if len(config.Traces) > 0 {
+		traces, err = initTraces(ctx, config)
+		if err != nil {
+			log.Fatalf("Error creating trace exporters: %v", err)
+		}
+		shutdowns = append(
+			shutdowns, runInBackground(ctx, traces.run, traces.shutdown))
+	}
+	if len(config.Logs) > 0 {
+		logs, err := initLogs(ctx, config, traces)
+		if err != nil {
+			log.Fatalf("Error creating logs exporter: %v", err)
+		}
+		shutdowns = append(
+			shutdowns, runInBackground(ctx, logs.run, logs.shutdown))
+	}
+
+	anomalyLog, err := newAnomalyLog(fAnomalyLog)
+	if err != nil {
+		log.Fatalf("Error creating anomaly log: %v", err)
+	}
+	defer anomalyLog.close()
+	var stream *gooteltest.ValueSetStream
+	if config.ValueSource != "" {
+		stream, err = config.OpenValueSource()
+		if err != nil {
+			log.Fatalf("Error opening value source: %v", err)
+		}
+		defer stream.Close()
+	}
+	state, playErr := play(
+		ctx, meters, config, report, anomalyLog, stream, resume)
+	if playErr != nil {
+		log.Printf("Error playing config: %v", playErr)
+	}
+	// state is nil if play could not resume.
+	if fState != "" && state != nil {
+		state.ConfigHash = hash
+		reportErr(state.writeFile(fState), "failed to write state")
+	}
+
+	// Shutting down a meter provider does a final export, so the report
+	// must be written after shutdown.
+	var wg sync.WaitGroup
+	for _, shutdown := range shutdowns {
+		wg.Add(1)
+		go func(shutdown func()) {
+			defer wg.Done()
+			shutdown()
+		}(shutdown)
+	}
+	wg.Wait()
+	if report != nil {
+		report.checkExported(config)
+		if err := report.writeFile(fReport, fReportFormat); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+	}
+	if playErr != nil {
+		// os.Exit skips deferred calls.
+		anomalyLog.close()
+		os.Exit(1)
+	}
 }
 
-func forever(meter metric.Meter,
+// runInBackground calls run in a new goroutine. It returns a function that
+// cancels the context passed to run, waits for run to return, and then
+// calls shutdown.
+func runInBackground(
+	ctx context.Context,
+	run func(context.Context),
+	shutdown func()) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+		shutdown()
+	}
+}
+
+// play plays the phases of config in order, and then from the beginning
+// again, until ctx is done or until -iterations value sets have been sent.
+// Each phase sends one of its MetricValueSets every collect period of the
+// phase. Pacing uses a ticker so that the time each iteration takes does
+// not add to the collect period. Each value is read from the handle of its
+// metric in the engine of the phase once and then recorded with every
+// meter. If resume is non-nil, play starts from there instead of from the
+// beginning. play returns where it stopped: the start of the first
+// iteration that was not completely sent. It returns an error if it
+// cannot resume or if stream has a value set it cannot read.
+func play(
+	ctx context.Context,
+	meters []metric.Meter,
 	config *gooteltest.Config,
-	engine *gooteltest.Engine,
-	prefix string) {
-	for {
+	report *runReport,
+	anomalyLog *anomalyLog,
+	stream *gooteltest.ValueSetStream,
+	resume *playState) (*playState, error) {
+	limiter := newPointLimiter(fPointsPerSecond)
+	faults := gooteltest.NewFaultInjector(config.Faults)
+	anomalies := gooteltest.NewAnomalies(config.Anomalies)
+	defer func() { anomalyLog.write(anomalies.End()) }()
+	phases := config.PlayPhases()
+	// The handle of each metric in each phase.
+	engines := gooteltest.NewPhaseEngines(phases)
+	handles := make([][]*gooteltest.Handle, len(phases))
+	for p, engine := range engines {
 		for _, m := range config.Metrics {
+			handles[p] = append(handles[p], engine.Handle(m.Name))
+		}
+	}
+	// Values from stream still need an engine for random observations.
+	streamEngine := gooteltest.NewEngine(nil)
+	start := time.Now()
+
+	// carry makes phase p start with the values that the phase before it
+	// ended with. Phases always play from their first value set, so how
+	// each phase ends is known.
+	carry := func(p int) {
+		prev := (p + len(phases) - 1) % len(phases)
+		engines[p].CarryFrom(engines[prev], phases[prev].Iterations())
+	}
+
+	// The values last sent by metric name. Ramps start from these.
+	last := make(map[string]float64)
+	firstPhase, firstIteration := 0, 0
+	// Whether the first phase has started again.
+	looped := false
+	var firstFrom map[string]float64
+	if resume != nil {
+		if resume.Phase >= len(phases) || len(resume.Engines) != len(engines) {
+			return nil, errors.New("state does not match the phases of config")
+		}
+		for p, engine := range engines {
+			if err := engine.Restore(resume.Engines[p]); err != nil {
+				return nil, err
+			}
+		}
+		if stream != nil {
+			if err := stream.Seek(resume.StreamPosition); err != nil {
+				return nil, err
+			}
+		}
+		for k, v := range resume.Last {
+			last[k] = v
+		}
+		firstPhase, firstIteration = resume.Phase, resume.PhaseIteration
+		firstFrom = resume.From
+		looped = resume.Looped
+		// Carry values up to the phase resumed, going round twice if
+		// the first phase has started again since it needs the values
+		// the last phase ended with.
+		if looped {
+			for q := 1; q < len(phases); q++ {
+				carry(q)
+			}
+			carry(0)
+		}
+		for q := 1; q <= firstPhase; q++ {
+			carry(q)
+		}
+	}
+
+	// state is updated before each iteration so that an iteration cut
+	// short is sent again in full on resume.
+	var state *playState
+	saveState := func(p, k int, from map[string]float64) {
+		if fState == "" {
+			return
+		}
+		state = &playState{
+			Phase:          p,
+			PhaseIteration: k,
+			Last:           make(map[string]float64, len(last)),
+			From:           from,
+			Looped:         looped,
+		}
+		for _, engine := range engines {
+			state.Engines = append(state.Engines, engine.Snapshot())
+		}
+		if stream != nil {
+			state.StreamPosition = stream.Position()
+		}
+		for k, v := range last {
+			state.Last[k] = v
+		}
+	}
+
+	ticker := time.NewTicker(phases[firstPhase].CollectPeriod)
+	defer ticker.Stop()
+	iteration := 0
+	for p := firstPhase; ; p = (p + 1) % len(phases) {
+		phase := phases[p]
+		ticker.Reset(phase.CollectPeriod)
+		from := make(map[string]float64, len(last))
+		for k, v := range last {
+			from[k] = v
+		}
+		k := 0
+		if p == firstPhase && iteration == 0 {
+			k = firstIteration
+			if firstFrom != nil {
+				from = firstFrom
+			}
+		} else {
+			if p == 0 {
+				looped = true
+			}
+			carry(p)
+			engines[p].Reset()
+		}
+		for ; phase.Iterations() == 0 || k < phase.Iterations(); k++ {
+			saveState(p, k, from)
+			if fIterations > 0 && iteration >= fIterations {
+				return state, nil
+			}
+			if iteration > 0 {
+				select {
+				case <-ctx.Done():
+					return state, nil
+				case <-ticker.C:
+				}
+			}
+			var streamValues map[string]gooteltest.MetricValue
+			if stream != nil {
+				var err error
+				if streamValues, err = stream.Next(); err != nil {
+					return state, err
+				}
+			}
+			if report != nil && stream != nil {
+				report.played(iteration, stream.Index())
+			} else if report != nil && len(phase.ValueSets) > 0 {
+				report.played(iteration, k%len(phase.ValueSets))
+			}
+			fraction := phase.RampFraction(k)
+			for i, m := range config.Metrics {
+				var sample gooteltest.Sample
+				if stream != nil {
+					sample = streamEngine.Sample(streamValues[m.Name])
+				} else {
+					sample = handles[p][i].NextSample()
+				}
+				if rampStart, ok := from[m.Name]; ok && fraction < 1 {
+					sample = sample.Ramp(rampStart, fraction)
+				}
+				last[m.Name] = sample.Value
+				sample, events := anomalies.Apply(
+					m.Name, time.Since(start), sample)
+				anomalyLog.write(events)
+				if !record(ctx, meters, config, m, sample, limiter, faults) {
+					return state, nil
+				}
+			}
+			iteration++
+		}
+	}
+}
+
+// record records sample for metric m with every meter. It returns false
+// if ctx is done.
+func record(
+	ctx context.Context,
+	meters []metric.Meter,
+	config *gooteltest.Config,
+	m gooteltest.MetricInfo,
+	sample gooteltest.Sample,
+	limiter *pointLimiter,
+	faults *gooteltest.FaultInjector) bool {
+	// The SDK applies views to what faults record.
+	name := faults.Name(config.InstrumentName(m))
+	opts := []metric.InstrumentOption{
+		metric.WithUnit(m.Unit),
+		metric.WithDescription(m.Description),
+	}
+	attrSets := [][]attribute.KeyValue{
+		toAttributes(faults.Attributes(m.Attributes)),
+	}
+	for _, extra := range faults.Burst() {
+		for k, v := range m.Attributes {
+			extra[k] = v
+		}
+		attrSets = append(attrSets, toAttributes(extra))
+	}
+	for _, meter := range meters {
+		for _, attrs := range attrSets {
+			if limiter.Wait(ctx) != nil {
+				return false
+			}
 			switch m.Type {
 			case gooteltest.MetricTypeGauge:
-				registerGaugeMetric(meter, m.Name, engine)
+				registerGaugeMetric(
+					context.Background(),
+					meter,
+					name,
+					opts,
+					faults.Value(sample.Value),
+					attrs...)
 			case gooteltest.MetricTypeSum:
-				registerSumMetric(meter, m.Name, prefix, engine)
+				registerSumMetric(
+					exemplarContext(config, sample),
+					meter,
+					name,
+					opts,
+					faults.Value(sample.Value),
+					attrs...)
 			case gooteltest.MetricTypeHistogram:
-				registerHistograms(meter, m.Name, prefix, engine)
+				registerHistograms(
+					exemplarContext(config, sample),
+					meter,
+					name,
+					opts,
+					faults.Observations(sample.Observations),
+					attrs...)
 			}
 		}
-		time.Sleep(config.CollectPeriod)
 	}
+	return true
 }
 
 func init() {
-	flag.StringVar(&fConfig, "config", "", "Config file path")
+	flag.Var(
+		&fConfig,
+		"config",
+		"Config file or directory. May be given more than once to merge several")
+	flag.Var(
+		fParams,
+		"set",
+		"Set a config placeholder as name=value. May be given more than once")
+	flag.StringVar(
+		&fReport, "report", "", "Write a run report to this path on exit")
+	flag.StringVar(
+		&fReportFormat,
+		"report-format",
+		"",
+		"Report format: json or junit. Default is junit for .xml files, otherwise json")
+	flag.DurationVar(
+		&fDuration, "duration", 0, "Stop after this long. 0 means run until interrupted")
+	flag.IntVar(
+		&fIterations,
+		"iterations",
+		0,
+		"Stop after sending this many value sets. 0 means no limit")
+	flag.Float64Var(
+		&fPointsPerSecond,
+		"max-points-per-second",
+		0,
+		"Cap on data points recorded per second across all metrics. 0 means no cap")
+	flag.StringVar(
+		&fAnomalyLog,
+		"anomaly-log",
+		"",
+		"Write a JSON line to this path each time an anomaly starts or ends")
+	flag.StringVar(
+		&fState,
+		"state",
+		"",
+		"Resume from the state in this file if it exists, and write the state to it on exit")
 }
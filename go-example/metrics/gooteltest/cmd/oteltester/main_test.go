@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// readConfig reads the config in yaml or fails t.
+func readConfig(t testing.TB, yaml string) *gooteltest.Config {
+	t.Helper()
+	config, err := gooteltest.ReadConfig(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// newTestProvider returns a meter provider with the views of config and a
+// manual reader to collect what it records.
+func newTestProvider(
+	t testing.TB,
+	config *gooteltest.Config,
+	opts ...sdkmetric.ManualReaderOption) (
+	*sdkmetric.MeterProvider, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader(opts...)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithView(newView(config)), sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider, reader
+}
+
+// playOnce records the next value set of config with provider.
+func playOnce(
+	t testing.TB,
+	provider *sdkmetric.MeterProvider,
+	config *gooteltest.Config,
+	engine *gooteltest.Engine,
+	faults *gooteltest.FaultInjector) {
+	t.Helper()
+	meters := []metric.Meter{provider.Meter(config.Meter)}
+	for _, m := range config.Metrics {
+		if !record(
+			context.Background(),
+			meters,
+			config,
+			m,
+			engine.NextSample(m.Name),
+			nil,
+			faults) {
+			t.Fatal("record gave up")
+		}
+	}
+}
+
+// collect collects the metrics read by reader by name.
+func collect(
+	t testing.TB,
+	reader *sdkmetric.ManualReader) map[string]metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			result[m.Name] = m
+		}
+	}
+	return result
+}
+
+func TestRecordInvalidInstrumentNames(t *testing.T) {
+	const metrics = `
+namePrefix: ""
+metrics:
+- name: "job:http_requests:rate5m"
+  type: "gauge"
+- name: "requests:total"
+  type: "sum"
+- name: "latency"
+  type: "histogram"
+valueSets:
+- valueSet:
+  - name: "job:http_requests:rate5m"
+    value: 2.5
+  - name: "requests:total"
+    value: 3
+  - name: "latency"
+    value: 1.5
+`
+	for _, tc := range []struct {
+		name   string
+		yaml   string
+		length int
+	}{
+		{name: "colons", yaml: metrics},
+		{
+			name:   "longName",
+			yaml:   metrics + "faults:\n  longName: 1\n  longLength: 300\n",
+			length: 300,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := readConfig(t, tc.yaml)
+			provider, reader := newTestProvider(t, config)
+			playOnce(
+				t,
+				provider,
+				config,
+				gooteltest.NewEngine(config.ValueSets),
+				gooteltest.NewFaultInjector(config.Faults))
+			got := collect(t, reader)
+			if len(got) != len(config.Metrics) {
+				t.Fatalf("Collected %d metrics, want %d",
+					len(got), len(config.Metrics))
+			}
+			for _, m := range config.Metrics {
+				name := m.Name
+				if tc.length > 0 {
+					name += strings.Repeat("x", tc.length-len(name))
+				}
+				if got[name].Data == nil {
+					t.Errorf("No data for %q", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// dialOTLP connects to the OTLP gRPC endpoint given by the environment
+// variable envVar or by OTEL_EXPORTER_OTLP_ENDPOINT. Default is
+// localhost:4317.
+func dialOTLP(ctx context.Context, envVar string) (*grpc.ClientConn, error) {
+	endpoint := os.Getenv(envVar)
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = "localhost:4317"
+	}
+	return dialEndpoint(ctx, endpoint)
+}
+
+// dialEndpoint connects to an OTLP gRPC endpoint. The connection is
+// insecure unless the endpoint starts with https://.
+func dialEndpoint(
+	ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if strings.HasPrefix(endpoint, "https://") {
+		creds = credentials.NewClientTLSFromCert(nil, "")
+	}
+	endpoint = strings.TrimPrefix(endpoint, "http://")
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+	return grpc.DialContext(
+		ctx, endpoint, grpc.WithTransportCredentials(creds))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pointLimiter caps the number of data points recorded per second across
+// all metrics. It is a token bucket holding at most one second's worth of
+// points, or one point if that is less than one. A nil *pointLimiter
+// places no limit.
+type pointLimiter struct {
+	perSecond float64
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newPointLimiter(perSecond float64) *pointLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &pointLimiter{
+		perSecond: perSecond,
+		tokens:    math.Max(perSecond, 1),
+		last:      time.Now(),
+	}
+}
+
+// Wait blocks until one more data point may be recorded or until ctx is
+// done. It returns ctx.Err() if ctx is done first.
+func (l *pointLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token and returns 0 if one is available. Otherwise it
+// returns how long to wait before trying again.
+func (l *pointLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.perSecond
+	// Below one point per second, the bucket must still fill up to one.
+	if capacity := math.Max(l.perSecond, 1); l.tokens > capacity {
+		l.tokens = capacity
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.perSecond * float64(time.Second))
+}
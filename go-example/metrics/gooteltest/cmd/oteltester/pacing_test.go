@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPointLimiterBelowOnePerSecond(t *testing.T) {
+	l := newPointLimiter(0.5)
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("First point waits %v", d)
+	}
+	if d := l.reserve(); d <= time.Second || d > 2*time.Second {
+		t.Errorf("Second point waits %v, want about 2s", d)
+	}
+	// Long after, the bucket holds one point, not half of one.
+	l.last = l.last.Add(-time.Minute)
+	if d := l.reserve(); d != 0 {
+		t.Errorf("Point after a minute waits %v", d)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+)
+
+// importPrometheusMain runs 'oteltester import-prometheus' which converts
+// Prometheus text exposition snapshots to a config that the tester can
+// play back.
+func importPrometheusMain(args []string) {
+	flags := flag.NewFlagSet("import-prometheus", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(
+			flags.Output(),
+			"Usage: oteltester import-prometheus [flags] [snapshot files...]")
+		flags.PrintDefaults()
+	}
+	out := flags.String("out", "", "Path of the config file to write")
+	period := flags.Duration(
+		"period", 10*time.Second, "Time between snapshots and collect period of the config")
+	service := flags.String(
+		"service", "", "service.name of the resource sending the metrics")
+	url := flags.String("url", "", "Scrape this /metrics URL instead of reading files")
+	count := flags.Int("count", 10, "Number of times to scrape -url")
+	_ = flags.Parse(args)
+	if *out == "" {
+		fmt.Println("Need to specify -out flag.")
+		flags.Usage()
+		os.Exit(1)
+	}
+	if (*url == "") == (flags.NArg() == 0) {
+		fmt.Println("Need either -url or snapshot files, but not both.")
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *period <= 0 {
+		log.Fatal("-period must be a positive duration")
+	}
+
+	var res map[string]string
+	if *service != "" {
+		res = map[string]string{"service.name": *service}
+	}
+	builder := gooteltest.NewConfigBuilder(*period)
+	add := func(name string, open func() (io.ReadCloser, error), t time.Time) {
+		r, err := open()
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", name, err)
+		}
+		defer r.Close()
+		if err := builder.AddPrometheus(r, t, res); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+	}
+
+	if *url != "" {
+		ctx, stop := signal.NotifyContext(
+			context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ticker := time.NewTicker(*period)
+		defer ticker.Stop()
+	scrape:
+		for i := 0; i < *count; i++ {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					break scrape
+				case <-ticker.C:
+				}
+			}
+			add(*url, func() (io.ReadCloser, error) {
+				resp, err := http.Get(*url)
+				if err != nil {
+					return nil, err
+				}
+				if resp.StatusCode != http.StatusOK {
+					resp.Body.Close()
+					return nil, fmt.Errorf("HTTP status %s", resp.Status)
+				}
+				return resp.Body, nil
+			}, time.Now())
+		}
+	} else {
+		// Snapshot files are taken to be one period apart.
+		start := time.Now()
+		for i, path := range flags.Args() {
+			path := path
+			add(path, func() (io.ReadCloser, error) {
+				return os.Open(path)
+			}, start.Add(time.Duration(i)*(*period)))
+		}
+	}
+
+	config, err := builder.Config()
+	if err != nil {
+		log.Fatalf("Error building config: %v", err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Error creating %s: %v", *out, err)
+	}
+	defer f.Close()
+	if err := gooteltest.WriteConfig(f, config); err != nil {
+		log.Fatalf("Error writing %s: %v", *out, err)
+	}
+	log.Printf(
+		"Wrote %d metrics and %d value sets to %s",
+		len(config.Metrics),
+		len(config.ValueSets),
+		*out)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// recorder is an OTLP metrics receiver that records everything it
+// receives into a gooteltest.ConfigBuilder.
+type recorder struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	// upstream is where received metrics are forwarded. It may be nil.
+	upstream colmetricspb.MetricsServiceClient
+
+	// lock protects the fields below.
+	lock    sync.Mutex
+	builder *gooteltest.ConfigBuilder
+	skipped map[string]bool
+}
+
+func (r *recorder) Export(
+	ctx context.Context,
+	request *colmetricspb.ExportMetricsServiceRequest,
+) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	r.add(request)
+	if r.upstream != nil {
+		return r.upstream.Export(ctx, request)
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *recorder) add(request *colmetricspb.ExportMetricsServiceRequest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.builder.AddOTLP(request.GetResourceMetrics()); err != nil {
+		// Report each problem once rather than once per export.
+		if !r.skipped[err.Error()] {
+			r.skipped[err.Error()] = true
+			log.Print(err)
+		}
+	}
+}
+
+func (r *recorder) config() (*gooteltest.Config, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.builder.Config()
+}
+
+// recordMain runs 'oteltester record' which receives OTLP metrics and
+// writes them out as a config that the tester can play back.
+func recordMain(args []string) {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	listen := flags.String("listen", ":4317", "Address to receive OTLP gRPC on")
+	out := flags.String("out", "", "Path of the config file to write")
+	forward := flags.String(
+		"forward", "", "OTLP gRPC endpoint to forward received metrics to")
+	period := flags.Duration(
+		"period", 10*time.Second, "Collect period of the recorded config")
+	duration := flags.Duration(
+		"duration", 0, "Stop after this long. 0 means record until interrupted")
+	_ = flags.Parse(args)
+	if *out == "" {
+		fmt.Println("Need to specify -out flag.")
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *period <= 0 {
+		log.Fatal("-period must be a positive duration")
+	}
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	rec := &recorder{
+		builder: gooteltest.NewConfigBuilder(*period),
+		skipped: make(map[string]bool),
+	}
+	if *forward != "" {
+		conn, err := dialEndpoint(ctx, *forward)
+		if err != nil {
+			log.Fatalf("Error connecting to %s: %v", *forward, err)
+		}
+		defer conn.Close()
+		rec.upstream = colmetricspb.NewMetricsServiceClient(conn)
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("Error listening on %s: %v", *listen, err)
+	}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, rec)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	log.Printf("Recording OTLP metrics on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("Error receiving OTLP: %v", err)
+	}
+
+	config, err := rec.config()
+	if err != nil {
+		log.Fatalf("Error building config: %v", err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Error creating %s: %v", *out, err)
+	}
+	defer f.Close()
+	if err := gooteltest.WriteConfig(f, config); err != nil {
+		log.Fatalf("Error writing %s: %v", *out, err)
+	}
+	log.Printf(
+		"Wrote %d metrics and %d value sets to %s",
+		len(config.Metrics),
+		len(config.ValueSets),
+		*out)
+}
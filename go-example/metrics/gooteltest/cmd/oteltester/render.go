@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+)
+
+// configPaths is a flag that may be given several times to merge several
+// config files or directories.
+type configPaths []string
+
+func (p *configPaths) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *configPaths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// renderMain runs 'oteltester render' which prints the config that
+// merging the given config files and their includes produces.
+func renderMain(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: oteltester render [flags] [file or directory ...]")
+		flags.PrintDefaults()
+	}
+	var paths configPaths
+	flags.Var(
+		&paths,
+		"config",
+		"Config file or directory to merge. May be given more than once")
+	params := gooteltest.Params{}
+	flags.Var(
+		params,
+		"set",
+		"Set a config placeholder as name=value. May be given more than once")
+	_ = flags.Parse(args)
+	paths = append(paths, flags.Args()...)
+	if len(paths) == 0 {
+		fmt.Println("Need to specify -config or config files.")
+		flags.Usage()
+		os.Exit(1)
+	}
+	config, err := gooteltest.ReadConfigFromFilesWithParams(params, paths...)
+	if err != nil {
+		log.Fatalf("Error opening config file: %v", err)
+	}
+	if err := gooteltest.WriteConfig(os.Stdout, config); err != nil {
+		log.Fatalf("Error writing config: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// replayer re-sends OTLP batches read from a file.
+type replayer struct {
+	metrics colmetricspb.MetricsServiceClient
+	traces  coltracepb.TraceServiceClient
+	logs    collogspb.LogsServiceClient
+
+	// speed is how many times faster than the original pace to send. 0
+	// means send as fast as possible.
+	speed float64
+
+	// rebase is true if timestamps are moved so that the first batch
+	// starts when the replay starts.
+	rebase bool
+}
+
+// scale returns how long after the replay starts something that happened
+// d after the first batch is sent.
+func (r *replayer) scale(d time.Duration) time.Duration {
+	if r.speed <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) / r.speed)
+}
+
+// run sends every batch in reader until it runs out or ctx is done. It
+// returns the number of batches sent.
+func (r *replayer) run(
+	ctx context.Context, reader *gooteltest.OTLPReader) (int, error) {
+	var first time.Time
+	var start time.Time
+	sent := 0
+	for {
+		batch, err := reader.Next()
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+		batchTime := batch.FirstTime()
+		if first.IsZero() {
+			first = batchTime
+			start = time.Now()
+		}
+		if r.rebase && !first.IsZero() {
+			batch.MapTimes(func(t time.Time) time.Time {
+				return start.Add(r.scale(t.Sub(first)))
+			})
+		}
+		if r.speed > 0 && !batchTime.IsZero() && !first.IsZero() {
+			wait := time.Until(start.Add(r.scale(batchTime.Sub(first))))
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return sent, nil
+				case <-time.After(wait):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return sent, nil
+		}
+		reportErr(r.send(ctx, batch), "failed to replay batch")
+		sent++
+	}
+}
+
+func (r *replayer) send(ctx context.Context, batch gooteltest.OTLPBatch) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var err error
+	switch {
+	case batch.Metrics != nil:
+		_, err = r.metrics.Export(ctx, batch.Metrics)
+	case batch.Traces != nil:
+		_, err = r.traces.Export(ctx, batch.Traces)
+	case batch.Logs != nil:
+		_, err = r.logs.Export(ctx, batch.Logs)
+	}
+	return err
+}
+
+// replayMain runs 'oteltester replay' which re-sends the OTLP batches in a
+// file written by the OTEL collector's file exporter.
+func replayMain(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: oteltester replay [flags] <file>")
+		flags.PrintDefaults()
+	}
+	rebase := flags.Bool(
+		"rebase", false, "Move timestamps so that the replay starts now")
+	speed := flags.Float64(
+		"speed", 1, "How many times faster than the original pace to send. 0 means as fast as possible")
+	signalName := flags.String(
+		"signal", gooteltest.SignalMetrics, "Signal of protobuf files: metrics, traces, or logs")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *speed < 0 {
+		log.Fatal("-speed must not be negative")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", flags.Arg(0), err)
+	}
+	defer f.Close()
+	reader, err := gooteltest.NewOTLPReader(f, *signalName)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", flags.Arg(0), err)
+	}
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	conns := make(map[string]*grpc.ClientConn)
+	for _, envVar := range []string{
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT",
+	} {
+		conn, err := dialOTLP(ctx, envVar)
+		if err != nil {
+			log.Fatalf("Error connecting to OTLP endpoint: %v", err)
+		}
+		defer conn.Close()
+		conns[envVar] = conn
+	}
+	r := &replayer{
+		metrics: colmetricspb.NewMetricsServiceClient(
+			conns["OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"]),
+		traces: coltracepb.NewTraceServiceClient(
+			conns["OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"]),
+		logs: collogspb.NewLogsServiceClient(
+			conns["OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"]),
+		speed:  *speed,
+		rebase: *rebase,
+	}
+	sent, err := r.run(ctx, reader)
+	if err != nil {
+		log.Fatalf("Error reading %s after %d batches: %v", flags.Arg(0), sent, err)
+	}
+	log.Printf("Replayed %d batches from %s", sent, flags.Arg(0))
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	reportFormatJSON  = "json"
+	reportFormatJUnit = "junit"
+)
+
+// reportPoint is a single exported data point.
+type reportPoint struct {
+	Metric      string            `json:"metric"`
+	Unit        string            `json:"unit,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Aggregation string            `json:"aggregation"`
+	Temporality string            `json:"temporality,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	StartTime   time.Time         `json:"startTime"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Value       *reportFloat      `json:"value,omitempty"`
+	Count       *uint64           `json:"count,omitempty"`
+	Sum         *reportFloat      `json:"sum,omitempty"`
+	Boundaries  []float64         `json:"boundaries,omitempty"`
+	Counts      []uint64          `json:"counts,omitempty"`
+	Scale       *int32            `json:"scale,omitempty"`
+	ZeroCount   *uint64           `json:"zeroCount,omitempty"`
+	Positive    *reportBuckets    `json:"positive,omitempty"`
+	Negative    *reportBuckets    `json:"negative,omitempty"`
+}
+
+// reportBuckets are the buckets of an exponential histogram for either
+// positive or negative values.
+type reportBuckets struct {
+	Offset int32    `json:"offset"`
+	Counts []uint64 `json:"counts"`
+}
+
+// reportFloat is a float64 that can hold NaN and infinities in JSON. They
+// are written as the strings "NaN", "+Inf", and "-Inf".
+type reportFloat float64
+
+func (f reportFloat) MarshalJSON() ([]byte, error) {
+	x := float64(f)
+	switch {
+	case math.IsNaN(x):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(x, 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(x, -1):
+		return []byte(`"-Inf"`), nil
+	}
+	return json.Marshal(x)
+}
+
+// reportError is an error returned by the exporter.
+type reportError struct {
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error"`
+}
+
+// reportExport is one call to the exporter.
+type reportExport struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Resource  map[string]string `json:"resource,omitempty"`
+	Points    []reportPoint     `json:"points"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// reportAssertion is the result of one check of the run.
+type reportAssertion struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// reportPlayed records which MetricValueSet was played and when.
+type reportPlayed struct {
+	Iteration int       `json:"iteration"`
+	ValueSet  int       `json:"valueSet"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// runReport is the record of a single oteltester run. It is safe to use
+// from multiple goroutines.
+type runReport struct {
+	lock sync.Mutex
+
+	ConfigHash   string         `json:"configHash"`
+	StartTime    time.Time      `json:"startTime"`
+	EndTime      time.Time      `json:"endTime"`
+	ValueSets    []reportPlayed `json:"valueSetsPlayed"`
+	Exports      []reportExport `json:"exports"`
+	ExportErrors []reportError  `json:"exportErrors"`
+
+	// The results of the checks made at the end of the run.
+	Assertions []reportAssertion `json:"assertions"`
+}
+
+func newRunReport(configHash string) *runReport {
+	return &runReport{
+		ConfigHash:   configHash,
+		StartTime:    time.Now(),
+		ValueSets:    []reportPlayed{},
+		Exports:      []reportExport{},
+		ExportErrors: []reportError{},
+		Assertions:   []reportAssertion{},
+	}
+}
+
+func (r *runReport) played(iteration, valueSet int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ValueSets = append(r.ValueSets, reportPlayed{
+		Iteration: iteration,
+		ValueSet:  valueSet,
+		Timestamp: time.Now(),
+	})
+}
+
+func (r *runReport) exported(
+	res *resource.Resource, points []reportPoint, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	e := reportExport{
+		Timestamp: now, Resource: attributeMap(res.Set()), Points: points}
+	if err != nil {
+		e.Error = err.Error()
+		r.ExportErrors = append(
+			r.ExportErrors, reportError{Timestamp: now, Error: err.Error()})
+	}
+	r.Exports = append(r.Exports, e)
+}
+
+// checkExported asserts that each metric of config that no view drops was
+// exported at least once under its exported name. A longName fault may
+// have padded the name.
+func (r *runReport) checkExported(config *gooteltest.Config) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	exported := make(map[string]bool)
+	for _, e := range r.Exports {
+		for _, point := range e.Points {
+			exported[point.Metric] = true
+		}
+	}
+	padded := config.Faults != nil && config.Faults.LongName > 0
+	for _, m := range config.Metrics {
+		view := config.ViewFor(config.InstrumentName(m))
+		if view.AggregationType() == gooteltest.AggregationDrop {
+			continue
+		}
+		name := config.ExportedName(m)
+		found := exported[name]
+		if !found && padded {
+			for metric := range exported {
+				if strings.HasPrefix(metric, name) {
+					found = true
+					break
+				}
+			}
+		}
+		assertion := reportAssertion{
+			Name:   fmt.Sprintf("exported-%s", m.Name),
+			Passed: found,
+		}
+		if !found {
+			assertion.Message = fmt.Sprintf(
+				"Metric '%s' was never exported as '%s'", m.Name, name)
+		}
+		r.Assertions = append(r.Assertions, assertion)
+	}
+}
+
+// writeFile writes this report to fileName in the given format. If format
+// is empty, it is inferred from the file extension: '.xml' means JUnit XML,
+// anything else means JSON.
+func (r *runReport) writeFile(fileName, format string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.EndTime.IsZero() {
+		r.EndTime = time.Now()
+	}
+	if format == "" {
+		format = reportFormatJSON
+		if strings.EqualFold(filepath.Ext(fileName), ".xml") {
+			format = reportFormatJUnit
+		}
+	}
+	var data []byte
+	var err error
+	switch format {
+	case reportFormatJSON:
+		data, err = json.MarshalIndent(r, "", "  ")
+	case reportFormatJUnit:
+		data, err = r.junit()
+	default:
+		return fmt.Errorf("Unknown report format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       float64         `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+// junit renders this report as a JUnit XML test suite with one test case
+// per export and one per assertion. Caller must hold the lock.
+func (r *runReport) junit() ([]byte, error) {
+	played := make([]string, len(r.ValueSets))
+	for i, p := range r.ValueSets {
+		played[i] = fmt.Sprint(p.ValueSet)
+	}
+	suite := junitTestSuite{
+		Name:      "oteltester",
+		Time:      r.EndTime.Sub(r.StartTime).Seconds(),
+		Timestamp: r.StartTime.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "configHash", Value: r.ConfigHash},
+			{Name: "valueSetsPlayed", Value: strings.Join(played, ",")},
+		},
+	}
+	last := r.StartTime
+	for i, e := range r.Exports {
+		points, err := json.MarshalIndent(e.Points, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("export-%d", i),
+			ClassName: "oteltester.export",
+			Time:      e.Timestamp.Sub(last).Seconds(),
+			SystemOut: string(points),
+		}
+		if e.Error != "" {
+			tc.Failure = &junitFailure{Message: e.Error, Text: e.Error}
+			suite.Failures++
+		}
+		last = e.Timestamp
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	for _, a := range r.Assertions {
+		tc := junitTestCase{Name: a.Name, ClassName: "oteltester.assertion"}
+		if !a.Passed {
+			tc.Failure = &junitFailure{Message: a.Message, Text: a.Message}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// reportingExporter wraps an exporter and records everything it exports
+// in a runReport.
+type reportingExporter struct {
+	sdkmetric.Exporter
+	report *runReport
+}
+
+func (e *reportingExporter) Export(
+	ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	// The reader reuses rm after Export returns, so points must not share
+	// memory with it.
+	points := collectPoints(rm)
+	err := e.Exporter.Export(ctx, rm)
+	e.report.exported(rm.Resource, points, err)
+	return err
+}
+
+func collectPoints(rm *metricdata.ResourceMetrics) []reportPoint {
+	points := []reportPoint{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points = append(points, newReportPoints(m)...)
+		}
+	}
+	return points
+}
+
+// newReportPoints returns the points of m. The tester only creates float
+// instruments, so m has float points.
+func newReportPoints(m metricdata.Metrics) []reportPoint {
+	var result []reportPoint
+	newPoint := func(
+		aggregation string,
+		temporality string,
+		attrs attribute.Set,
+		startTime, timestamp time.Time) reportPoint {
+		return reportPoint{
+			Metric:      m.Name,
+			Unit:        m.Unit,
+			Description: m.Description,
+			Aggregation: aggregation,
+			Temporality: temporality,
+			Attributes:  attributeMap(&attrs),
+			StartTime:   startTime,
+			Timestamp:   timestamp,
+		}
+	}
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			point := newPoint(
+				gooteltest.AggregationLastValue,
+				"",
+				dp.Attributes,
+				dp.StartTime,
+				dp.Time)
+			point.Value = floatPtr(dp.Value)
+			result = append(result, point)
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			point := newPoint(
+				gooteltest.AggregationSum,
+				temporalityName(data.Temporality),
+				dp.Attributes,
+				dp.StartTime,
+				dp.Time)
+			point.Value = floatPtr(dp.Value)
+			result = append(result, point)
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			point := newPoint(
+				gooteltest.AggregationExplicitHistogram,
+				temporalityName(data.Temporality),
+				dp.Attributes,
+				dp.StartTime,
+				dp.Time)
+			count := dp.Count
+			point.Count = &count
+			point.Sum = floatPtr(dp.Sum)
+			point.Boundaries = append([]float64(nil), dp.Bounds...)
+			point.Counts = append([]uint64(nil), dp.BucketCounts...)
+			result = append(result, point)
+		}
+	case metricdata.ExponentialHistogram[float64]:
+		for _, dp := range data.DataPoints {
+			point := newPoint(
+				gooteltest.AggregationExponentialHistogram,
+				temporalityName(data.Temporality),
+				dp.Attributes,
+				dp.StartTime,
+				dp.Time)
+			count, scale, zeroCount := dp.Count, dp.Scale, dp.ZeroCount
+			point.Count = &count
+			point.Sum = floatPtr(dp.Sum)
+			point.Scale = &scale
+			point.ZeroCount = &zeroCount
+			point.Positive = newReportBuckets(dp.PositiveBucket)
+			point.Negative = newReportBuckets(dp.NegativeBucket)
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+func newReportBuckets(b metricdata.ExponentialBucket) *reportBuckets {
+	if len(b.Counts) == 0 {
+		return nil
+	}
+	return &reportBuckets{
+		Offset: b.Offset,
+		Counts: append([]uint64(nil), b.Counts...),
+	}
+}
+
+// temporalityName returns "cumulative" or "delta".
+func temporalityName(t metricdata.Temporality) string {
+	return strings.ToLower(strings.TrimSuffix(t.String(), "Temporality"))
+}
+
+// attributeMap returns attrs as a map or nil if attrs is empty.
+func attributeMap(attrs *attribute.Set) map[string]string {
+	if attrs.Len() == 0 {
+		return nil
+	}
+	result := make(map[string]string, attrs.Len())
+	for iter := attrs.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		result[string(kv.Key)] = kv.Value.Emit()
+	}
+	return result
+}
+
+func floatPtr(x float64) *reportFloat {
+	result := reportFloat(x)
+	return &result
+}
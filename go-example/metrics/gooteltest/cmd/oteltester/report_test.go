@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const reportConfig = `
+namePrefix: ""
+metrics:
+- name: "temperature"
+  type: "gauge"
+- name: "requests"
+  type: "sum"
+- name: "latency"
+  type: "histogram"
+- name: "size"
+  type: "histogram"
+- name: "debug"
+  type: "sum"
+valueSets:
+- valueSet:
+  - name: "temperature"
+    value: 21.5
+  - name: "requests"
+    value: 3
+  - name: "latency"
+    value: 1.5
+  - name: "size"
+    value: 3
+  - name: "debug"
+    value: 1
+views:
+- instrument: "size"
+  aggregation:
+    type: "exponentialHistogram"
+- instrument: "debug"
+  aggregation:
+    type: "drop"
+`
+
+// reportRun plays the first value set of config and returns the report
+// of its export.
+func reportRun(t *testing.T, config *gooteltest.Config) *runReport {
+	t.Helper()
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("hash")
+	report.exported(resource.Empty(), collectPoints(&rm), nil)
+	return report
+}
+
+func TestReportAggregations(t *testing.T) {
+	report := reportRun(t, readConfig(t, reportConfig))
+	got := make(map[string]string)
+	for _, point := range report.Exports[0].Points {
+		got[point.Metric] = point.Aggregation
+	}
+	want := map[string]string{
+		"temperature": gooteltest.AggregationLastValue,
+		"requests":    gooteltest.AggregationSum,
+		"latency":     gooteltest.AggregationExplicitHistogram,
+		"size":        gooteltest.AggregationExponentialHistogram,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+	for metric, aggregation := range want {
+		if got[metric] != aggregation {
+			t.Errorf("%s: got aggregation %q, want %q",
+				metric, got[metric], aggregation)
+		}
+	}
+}
+
+func TestReportCheckExported(t *testing.T) {
+	config := readConfig(t, reportConfig)
+	report := reportRun(t, config)
+	// A metric that the run never recorded.
+	config.Metrics = append(config.Metrics, gooteltest.MetricInfo{
+		Name: "queue", Type: gooteltest.MetricTypeGauge})
+	report.checkExported(config)
+	want := []reportAssertion{
+		{Name: "exported-temperature", Passed: true},
+		{Name: "exported-requests", Passed: true},
+		{Name: "exported-latency", Passed: true},
+		{Name: "exported-size", Passed: true},
+		{
+			Name:    "exported-queue",
+			Message: "Metric 'queue' was never exported as 'queue'",
+		},
+	}
+	if len(report.Assertions) != len(want) {
+		t.Fatalf("Got %+v, want %+v", report.Assertions, want)
+	}
+	for i, a := range want {
+		if report.Assertions[i] != a {
+			t.Errorf("Assertion %d: got %+v, want %+v",
+				i, report.Assertions[i], a)
+		}
+	}
+	data, err := report.junit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	junit := string(data)
+	for _, s := range []string{
+		`tests="6" failures="1"`,
+		`<testcase name="exported-queue" classname="oteltester.assertion"`,
+		`<failure message="Metric &#39;queue&#39; was never exported as &#39;queue&#39;">`,
+	} {
+		if !strings.Contains(junit, s) {
+			t.Errorf("JUnit report has no %s:\n%s", s, junit)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+)
+
+// playState is where play is in a config so that a later run can resume
+// from there. It is written with -state on exit.
+type playState struct {
+
+	// The hash of the config played so that a state is never resumed with
+	// a different config.
+	ConfigHash string `json:"configHash"`
+
+	// The phase and the iteration within it to play next.
+	Phase          int `json:"phase"`
+	PhaseIteration int `json:"phaseIteration"`
+
+	// Whether the first phase has started again since the run began.
+	Looped bool `json:"looped,omitempty"`
+
+	// Where the engine of each phase is.
+	Engines []gooteltest.EngineSnapshot `json:"engines"`
+
+	// The index of the next value set in the value source.
+	StreamPosition int `json:"streamPosition,omitempty"`
+
+	// The values last sent by metric name, and the values sent before the
+	// phase started, for ramps.
+	Last map[string]float64 `json:"last,omitempty"`
+	From map[string]float64 `json:"from,omitempty"`
+}
+
+// readPlayState reads the state at path. It returns nil if there is no
+// file at path.
+func readPlayState(path, configHash string) (*playState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result playState
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if result.ConfigHash != configHash {
+		return nil, fmt.Errorf("%s was written for a different config", path)
+	}
+	return &result, nil
+}
+
+func (s *playState) writeFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func toTemporality(selector string) metricdata.Temporality {
+	if selector == gooteltest.DeltaAggregationSelector {
+		return metricdata.DeltaTemporality
+	}
+	return metricdata.CumulativeTemporality
+}
+
+// temporalitySelector returns a selector that gives every instrument
+// temporality.
+func temporalitySelector(
+	temporality metricdata.Temporality) sdkmetric.TemporalitySelector {
+	return func(sdkmetric.InstrumentKind) metricdata.Temporality {
+		return temporality
+	}
+}
+
+// otherTemporalityNames returns the exported names of the metrics of
+// config whose temporality is not the default one.
+func otherTemporalityNames(config *gooteltest.Config) map[string]bool {
+	result := make(map[string]bool)
+	for _, m := range config.Metrics {
+		if m.Temporality != "" &&
+			m.Temporality != config.AggregationTemporalitySelector {
+			result[config.ExportedName(m)] = true
+		}
+	}
+	return result
+}
+
+// mixedTemporalityExporter exports metrics that do not all have the same
+// temporality in one request per collection. The SDK chooses temporality
+// per reader, so the metrics with the default temporality come from the
+// periodic reader that calls Export and the others from reader, a manual
+// reader with the other temporality. Both readers aggregate every
+// instrument; each export keeps the metrics from the reader with the
+// right temporality.
+type mixedTemporalityExporter struct {
+	sdkmetric.Exporter
+	reader *sdkmetric.ManualReader
+
+	// The exported names of the metrics that come from reader.
+	names map[string]bool
+}
+
+func (e *mixedTemporalityExporter) Export(
+	ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	var other metricdata.ResourceMetrics
+	if err := e.reader.Collect(ctx, &other); err != nil {
+		return err
+	}
+	result := &metricdata.ResourceMetrics{Resource: rm.Resource}
+	// By scope, the index in result.ScopeMetrics.
+	scopes := make(map[string]int)
+	add := func(sms []metricdata.ScopeMetrics, fromReader bool) {
+		for _, sm := range sms {
+			key := sm.Scope.Name + "\x00" + sm.Scope.Version
+			i, ok := scopes[key]
+			if !ok {
+				i = len(result.ScopeMetrics)
+				scopes[key] = i
+				result.ScopeMetrics = append(
+					result.ScopeMetrics, metricdata.ScopeMetrics{Scope: sm.Scope})
+			}
+			for _, m := range sm.Metrics {
+				if e.names[m.Name] == fromReader {
+					result.ScopeMetrics[i].Metrics = append(
+						result.ScopeMetrics[i].Metrics, m)
+				}
+			}
+		}
+	}
+	add(rm.ScopeMetrics, false)
+	add(other.ScopeMetrics, true)
+	return e.Exporter.Export(ctx, result)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// sumPoint is the single point of a sum as it was exported.
+type sumPoint struct {
+	temporality metricdata.Temporality
+	value       float64
+}
+
+// sumExporter records the sums in each export by name. The SDK reuses
+// what it exports, so the points are copied out during Export.
+type sumExporter struct {
+	temporality metricdata.Temporality
+	exports     []map[string]sumPoint
+}
+
+func (e *sumExporter) Temporality(
+	sdkmetric.InstrumentKind) metricdata.Temporality {
+	return e.temporality
+}
+
+func (e *sumExporter) Aggregation(
+	kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+func (e *sumExporter) Export(
+	_ context.Context, rm *metricdata.ResourceMetrics) error {
+	sums := make(map[string]sumPoint)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[float64])
+			if !ok || len(sum.DataPoints) != 1 {
+				continue
+			}
+			sums[m.Name] = sumPoint{
+				temporality: sum.Temporality,
+				value:       sum.DataPoints[0].Value,
+			}
+		}
+	}
+	e.exports = append(e.exports, sums)
+	return nil
+}
+
+func (e *sumExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *sumExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestMixedTemporality(t *testing.T) {
+	config := readConfig(t, `
+collectPeriod: 1h
+aggregationTemporalitySelector: "cumulative"
+namePrefix: ""
+metrics:
+- name: "requests"
+  type: "sum"
+- name: "errors"
+  type: "sum"
+  temporality: "delta"
+valueSets:
+- valueSet:
+  - name: "requests"
+    value: 3
+  - name: "errors"
+    value: 1
+- valueSet:
+  - name: "requests"
+    value: 4
+  - name: "errors"
+    value: 2
+`)
+	exporter := &sumExporter{temporality: metricdata.CumulativeTemporality}
+	provider := newMeterProvider(config, resource.Empty(), exporter)
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+	engine := gooteltest.NewEngine(config.ValueSets)
+	for range config.ValueSets {
+		playOnce(t, provider, config, engine, nil)
+		if err := provider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cumulative := metricdata.CumulativeTemporality
+	delta := metricdata.DeltaTemporality
+	want := []map[string]sumPoint{
+		{
+			"requests": {temporality: cumulative, value: 3},
+			"errors":   {temporality: delta, value: 1},
+		},
+		{
+			"requests": {temporality: cumulative, value: 7},
+			"errors":   {temporality: delta, value: 2},
+		},
+	}
+	if len(exporter.exports) != len(want) {
+		t.Fatalf("Got %d exports, want %d", len(exporter.exports), len(want))
+	}
+	for i, sums := range want {
+		if len(exporter.exports[i]) != len(sums) {
+			t.Errorf("Export %d: got %v, want %v", i, exporter.exports[i], sums)
+			continue
+		}
+		for name, point := range sums {
+			if got := exporter.exports[i][name]; got != point {
+				t.Errorf("Export %d of %s: got %+v, want %+v", i, name, got, point)
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var spanKinds = map[string]trace.SpanKind{
+	gooteltest.SpanKindInternal: trace.SpanKindInternal,
+	gooteltest.SpanKindServer:   trace.SpanKindServer,
+	gooteltest.SpanKindClient:   trace.SpanKindClient,
+	gooteltest.SpanKindProducer: trace.SpanKindProducer,
+	gooteltest.SpanKindConsumer: trace.SpanKindConsumer,
+}
+
+// traceSender sends the trace scenarios in the config. Each service that
+// sends spans has its own tracer provider, and configured services have
+// one per replica.
+type traceSender struct {
+	config *gooteltest.Config
+
+	// tracers by service name. The empty name is the default service.
+	tracers map[string][]trace.Tracer
+
+	providers []*sdktrace.TracerProvider
+
+	// lock protects the fields below.
+	lock sync.Mutex
+
+	// The span contexts of the last trace sent for each scenario, by
+	// scenario name and then span name.
+	lastSpans map[string]map[string]trace.SpanContext
+}
+
+// initTraces starts the connection with the OTEL collector for the
+// traces in config.
+func initTraces(
+	ctx context.Context, config *gooteltest.Config) (*traceSender, error) {
+	result := &traceSender{
+		config:    config,
+		tracers:   make(map[string][]trace.Tracer),
+		lastSpans: make(map[string]map[string]trace.SpanContext),
+	}
+	for _, scenario := range config.Traces {
+		if err := result.addTracers(ctx, &scenario.Root); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (t *traceSender) addTracers(
+	ctx context.Context, span *gooteltest.SpanInfo) error {
+	if _, ok := t.tracers[span.Service]; !ok {
+		resources, err := serviceResources(ctx, t.config, span.Service)
+		if err != nil {
+			return err
+		}
+		for _, res := range resources {
+			tracer, err := t.newTracer(ctx, res)
+			if err != nil {
+				return err
+			}
+			t.tracers[span.Service] = append(t.tracers[span.Service], tracer)
+		}
+	}
+	for i := range span.Children {
+		if err := t.addTracers(ctx, &span.Children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *traceSender) newTracer(
+	ctx context.Context, res *resource.Resource) (trace.Tracer, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	t.providers = append(t.providers, provider)
+	return provider.Tracer("oteltester"), nil
+}
+
+// run sends each scenario at its rate until ctx is done.
+func (t *traceSender) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, scenario := range t.config.Traces {
+		wg.Add(1)
+		go func(scenario gooteltest.TraceScenario) {
+			defer wg.Done()
+			ticker := time.NewTicker(
+				time.Duration(float64(time.Second) / scenario.Rate))
+			defer ticker.Stop()
+			for count := 0; ; count++ {
+				t.send(scenario, count)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(scenario)
+	}
+	wg.Wait()
+}
+
+// shutdown flushes all pending spans.
+func (t *traceSender) shutdown() {
+	for _, provider := range t.providers {
+		reportErr(
+			provider.Shutdown(context.Background()),
+			"failed to stop tracer provider")
+	}
+}
+
+// send sends one trace of scenario. count is the number of traces of the
+// scenario sent so far. It picks which replica sends each span.
+func (t *traceSender) send(scenario gooteltest.TraceScenario, count int) {
+	spans := make(map[string]trace.SpanContext)
+	t.sendSpan(
+		context.Background(),
+		scenario.Name,
+		&scenario.Root,
+		"",
+		time.Now(),
+		count,
+		spans)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastSpans[scenario.Name] = spans
+}
+
+func (t *traceSender) sendSpan(
+	ctx context.Context,
+	scenario string,
+	span *gooteltest.SpanInfo,
+	parentService string,
+	parentStart time.Time,
+	count int,
+	spans map[string]trace.SpanContext) {
+	service := span.Service
+	if service == "" {
+		service = parentService
+	}
+	tracers := t.tracers[service]
+	if len(tracers) == 0 {
+		log.Printf("no tracer for service '%s'", service)
+		return
+	}
+	tracer := tracers[count%len(tracers)]
+	start := parentStart.Add(span.Offset)
+	ctx, s := tracer.Start(
+		ctx,
+		span.Name,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(spanKinds[span.Kind]),
+		trace.WithAttributes(toAttributes(span.Attributes)...),
+		trace.WithLinks(t.links(scenario, span.Links, spans)...),
+	)
+	for _, event := range span.Events {
+		s.AddEvent(
+			event.Name,
+			trace.WithTimestamp(start.Add(event.Offset)),
+			trace.WithAttributes(toAttributes(event.Attributes)...))
+	}
+	if span.Error != "" {
+		s.SetStatus(codes.Error, span.Error)
+	}
+	spans[span.Name] = s.SpanContext()
+	for i := range span.Children {
+		t.sendSpan(ctx, scenario, &span.Children[i], service, start, count, spans)
+	}
+	s.End(trace.WithTimestamp(start.Add(span.Duration)))
+}
+
+// links resolves the links of a span. Links to spans in the trace being
+// sent resolve to spans already sent in that trace; otherwise they
+// resolve to the last trace sent for the scenario. Links that don't
+// resolve yet are left out.
+func (t *traceSender) links(
+	scenario string,
+	links []gooteltest.SpanLink,
+	spans map[string]trace.SpanContext) []trace.Link {
+	var result []trace.Link
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, link := range links {
+		target := link.Scenario
+		if target == "" {
+			target = scenario
+		}
+		var sc trace.SpanContext
+		var ok bool
+		if target == scenario {
+			sc, ok = spans[link.Span]
+		}
+		if !ok {
+			sc, ok = t.lastSpans[target][link.Span]
+		}
+		if !ok {
+			continue
+		}
+		result = append(result, trace.Link{
+			SpanContext: sc,
+			Attributes:  toAttributes(link.Attributes),
+		})
+	}
+	return result
+}
+
+// lastSpan returns the span context of the span with the given name in the
+// most recently sent trace of scenario. If span is empty, it means the root
+// span.
+func (t *traceSender) lastSpan(
+	scenario, span string) (trace.SpanContext, bool) {
+	if span == "" {
+		for _, s := range t.config.Traces {
+			if s.Name == scenario {
+				span = s.Root.Name
+			}
+		}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	sc, ok := t.lastSpans[scenario][span]
+	return sc, ok
+}
+
+func toAttributes(attrs map[string]string) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		result = append(result, attribute.String(k, v))
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// newView returns the SDK view that applies the views of config. Unlike
+// SDK views, only the first view of config that matches an instrument
+// applies to it, so all of them go into this single SDK view. It also
+// gives each histogram the explicit boundaries of the metric it was
+// created for, or DefaultBoundaries.
+func newView(config *gooteltest.Config) sdkmetric.View {
+	// By instrument name.
+	boundaries := make(map[string][]float64)
+	for _, m := range config.Metrics {
+		if m.Boundaries != nil {
+			boundaries[config.InstrumentName(m)] = m.Boundaries
+		}
+	}
+	histogram := func(name string) sdkmetric.Aggregation {
+		result, ok := boundaries[name]
+		if !ok {
+			result = gooteltest.DefaultBoundaries
+		}
+		return sdkmetric.AggregationExplicitBucketHistogram{Boundaries: result}
+	}
+	return func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		stream := sdkmetric.Stream{
+			Name:        inst.Name,
+			Description: inst.Description,
+			Unit:        inst.Unit,
+		}
+		if inst.Kind == sdkmetric.InstrumentKindHistogram {
+			stream.Aggregation = histogram(inst.Name)
+		}
+		view := config.ViewFor(inst.Name)
+		if view == nil {
+			return stream, true
+		}
+		stream.Name = view.ExportedName(inst.Name)
+		if len(view.AllowAttributes) > 0 || len(view.DenyAttributes) > 0 {
+			stream.AttributeFilter = func(kv attribute.KeyValue) bool {
+				return view.KeepsAttribute(string(kv.Key))
+			}
+		}
+		switch a := view.Aggregation; view.AggregationType() {
+		case gooteltest.AggregationDrop:
+			stream.Aggregation = sdkmetric.AggregationDrop{}
+		case gooteltest.AggregationSum:
+			stream.Aggregation = sdkmetric.AggregationSum{}
+		case gooteltest.AggregationLastValue:
+			stream.Aggregation = sdkmetric.AggregationLastValue{}
+		case gooteltest.AggregationExplicitHistogram:
+			stream.Aggregation = histogram(inst.Name)
+			if a.Boundaries != nil {
+				stream.Aggregation = sdkmetric.AggregationExplicitBucketHistogram{
+					Boundaries: a.Boundaries}
+			}
+		case gooteltest.AggregationExponentialHistogram:
+			stream.Aggregation = sdkmetric.AggregationBase2ExponentialHistogram{
+				MaxSize:  a.MaxSize,
+				MaxScale: *a.MaxScale,
+			}
+		}
+		return stream, true
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/wavefronthq/opentelemetry-examples/go-example/metrics/gooteltest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+)
+
+// assertCollected checks that got has exactly the metrics in want.
+func assertCollected(
+	t *testing.T, want []metricdata.Metrics, got map[string]metricdata.Metrics) {
+	t.Helper()
+	for _, m := range want {
+		actual, ok := got[m.Name]
+		if !ok {
+			t.Errorf("Metric %q not collected", m.Name)
+			continue
+		}
+		metricdatatest.AssertEqual(t, m, actual, metricdatatest.IgnoreTimestamp())
+	}
+	if len(got) != len(want) {
+		t.Errorf("Collected %d metrics, want %d", len(got), len(want))
+	}
+}
+
+func TestRecordDefaultAggregations(t *testing.T) {
+	config := readConfig(t, `
+metrics:
+- name: "temperature"
+  type: "gauge"
+  unit: "Cel"
+  description: "Room temperature"
+  attributes:
+    room: "kitchen"
+- name: "requests"
+  type: "sum"
+  unit: "{request}"
+- name: "latency"
+  type: "histogram"
+  unit: "s"
+  boundaries: [0.5, 1, 2]
+- name: "size"
+  type: "histogram"
+valueSets:
+- valueSet:
+  - name: "temperature"
+    value: 21.5
+  - name: "requests"
+    value: 3
+  - name: "latency"
+    observations: [0.2, 1.5, 1.8, 7]
+  - name: "size"
+    value: 3
+`)
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	assertCollected(t, []metricdata.Metrics{
+		{
+			Name:        "temperature",
+			Description: "Room temperature",
+			Unit:        "Cel",
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{{
+					Attributes: attribute.NewSet(
+						attribute.String("room", "kitchen")),
+					Value: 21.5,
+				}},
+			},
+		},
+		{
+			Name: "cum_requests",
+			Unit: "{request}",
+			Data: metricdata.Sum[float64]{
+				DataPoints:  []metricdata.DataPoint[float64]{{Value: 3}},
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			},
+		},
+		{
+			Name: "cum_latency",
+			Unit: "s",
+			Data: metricdata.Histogram[float64]{
+				DataPoints: []metricdata.HistogramDataPoint[float64]{{
+					Count:        4,
+					Bounds:       []float64{0.5, 1, 2},
+					BucketCounts: []uint64{1, 0, 2, 1},
+					Min:          metricdata.NewExtrema(0.2),
+					Max:          metricdata.NewExtrema(7.0),
+					Sum:          10.5,
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		},
+		{
+			Name: "cum_size",
+			Data: metricdata.Histogram[float64]{
+				DataPoints: []metricdata.HistogramDataPoint[float64]{{
+					Count:        1,
+					Bounds:       gooteltest.DefaultBoundaries,
+					BucketCounts: []uint64{0, 0, 1, 0, 0},
+					Min:          metricdata.NewExtrema(3.0),
+					Max:          metricdata.NewExtrema(3.0),
+					Sum:          3,
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		},
+	}, collect(t, reader))
+}
+
+func TestRecordViews(t *testing.T) {
+	config := readConfig(t, `
+namePrefix: ""
+metrics:
+- name: "requests"
+  type: "sum"
+  attributes:
+    host: "a"
+    region: "east"
+- name: "errors"
+  type: "sum"
+  attributes:
+    host: "a"
+    region: "east"
+- name: "debug.calls"
+  type: "sum"
+- name: "queue"
+  type: "gauge"
+- name: "latency"
+  type: "histogram"
+valueSets:
+- valueSet:
+  - name: "requests"
+    value: 3
+  - name: "errors"
+    value: 1
+  - name: "debug.calls"
+    value: 8
+  - name: "queue"
+    value: 4
+  - name: "latency"
+    observations: [0.2, 3]
+views:
+- instrument: "requests"
+  name: "http.requests"
+  allowAttributes: ["host"]
+- instrument: "errors"
+  denyAttributes: ["region"]
+- instrument: "debug.*"
+  aggregation:
+    type: "drop"
+- instrument: "queue"
+  aggregation:
+    type: "lastValue"
+- instrument: "latency"
+  aggregation:
+    type: "explicitHistogram"
+    boundaries: [1]
+`)
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	host := attribute.NewSet(attribute.String("host", "a"))
+	assertCollected(t, []metricdata.Metrics{
+		{
+			Name: "http.requests",
+			Data: metricdata.Sum[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{{
+					Attributes: host,
+					Value:      3,
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			},
+		},
+		{
+			Name: "errors",
+			Data: metricdata.Sum[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{{
+					Attributes: host,
+					Value:      1,
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			},
+		},
+		{
+			Name: "queue",
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{{Value: 4}},
+			},
+		},
+		{
+			Name: "latency",
+			Data: metricdata.Histogram[float64]{
+				DataPoints: []metricdata.HistogramDataPoint[float64]{{
+					Count:        2,
+					Bounds:       []float64{1},
+					BucketCounts: []uint64{1, 1},
+					Min:          metricdata.NewExtrema(0.2),
+					Max:          metricdata.NewExtrema(3.0),
+					Sum:          3.2,
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		},
+	}, collect(t, reader))
+}
+
+func TestRecordExponentialHistogram(t *testing.T) {
+	config := readConfig(t, `
+namePrefix: ""
+metrics:
+- name: "latency"
+  type: "histogram"
+- name: "size"
+  type: "histogram"
+valueSets:
+- valueSet:
+  - name: "latency"
+    observations: [0, 1, 2, 3, 4, 0.25]
+  - name: "size"
+    observations: [1, 1000000]
+views:
+- instrument: "latency"
+  aggregation:
+    type: "exponentialHistogram"
+    maxScale: 0
+- instrument: "size"
+  aggregation:
+    type: "exponentialHistogram"
+    maxSize: 4
+`)
+	provider, reader := newTestProvider(t, config)
+	playOnce(
+		t, provider, config, gooteltest.NewEngine(config.ValueSets), nil)
+	// At scale 0, bucket i holds values in (2^i, 2^(i+1)]. Keeping 1 and
+	// 1000000 apart, about 2^20 apart, in 4 buckets takes scale -3.
+	assertCollected(t, []metricdata.Metrics{
+		{
+			Name: "latency",
+			Data: metricdata.ExponentialHistogram[float64]{
+				DataPoints: []metricdata.ExponentialHistogramDataPoint[float64]{{
+					Count:     6,
+					Min:       metricdata.NewExtrema(0.0),
+					Max:       metricdata.NewExtrema(4.0),
+					Sum:       10.25,
+					Scale:     0,
+					ZeroCount: 1,
+					PositiveBucket: metricdata.ExponentialBucket{
+						Offset: -3,
+						Counts: []uint64{1, 0, 1, 1, 2},
+					},
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		},
+		{
+			Name: "size",
+			Data: metricdata.ExponentialHistogram[float64]{
+				DataPoints: []metricdata.ExponentialHistogramDataPoint[float64]{{
+					Count: 2,
+					Min:   metricdata.NewExtrema(1.0),
+					Max:   metricdata.NewExtrema(1000000.0),
+					Sum:   1000001,
+					Scale: -3,
+					PositiveBucket: metricdata.ExponentialBucket{
+						Offset: -1,
+						Counts: []uint64{1, 0, 0, 1},
+					},
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		},
+	}, collect(t, reader))
+}
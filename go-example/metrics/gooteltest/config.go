@@ -1,11 +1,15 @@
 package gooteltest
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -23,16 +27,69 @@ var metricTypeNames = map[string]bool{
 	MetricTypeGauge: true, MetricTypeSum: true, MetricTypeHistogram: true}
 
 // MetricInfo gives the name and type of particular metric. type must be
-// 'gauge', 'sum', or 'histogram'
+// 'gauge', 'sum', or 'histogram'. temporality is 'delta' or 'cumulative'
+// and applies only to sums and histograms. It defaults to the
+// aggregationTemporalitySelector in Config.
 type MetricInfo struct {
-	Name string `yaml:"name"`
-	Type string `yaml:"type"`
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Temporality string `yaml:"temporality,omitempty"`
+
+	// The name of the instrument this metric is sent as. Default is Name.
+	// Several metrics with different attributes may share an instrument
+	// if they have the same type, temporality, boundaries, unit, and
+	// description.
+	Instrument string `yaml:"instrument,omitempty"`
+
+	// The attributes recorded with every value of this metric.
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+
+	// The explicit bucket boundaries of a histogram. Default is
+	// DefaultBoundaries.
+	Boundaries []float64 `yaml:"boundaries,omitempty"`
+
+	// The unit of the instrument in UCUM syntax, such as 'ms' or 'By'.
+	// See CheckUnit. Optional.
+	Unit string `yaml:"unit,omitempty"`
+
+	// The description of the instrument. Optional.
+	Description string `yaml:"description,omitempty"`
 }
 
-// MetricValue is a metric name metric value pair.
+// DefaultBoundaries are the bucket boundaries of histograms that don't
+// give their own.
+var DefaultBoundaries = []float64{1.0, 2.0, 5.0, 10.0}
+
+// MetricValue is a metric name metric value pair. A histogram may instead
+// give several observations to record in the same collect period, either
+// as a list in Observations or as a number of Samples drawn from a
+// distribution in Dist such as 'lognormal(3, 0.5)'. See ParseDistribution.
+// Instead of a fixed value, any metric may follow a Seasonal pattern.
 type MetricValue struct {
-	Name  string  `yaml:"name"`
-	Value float64 `yaml:"value"`
+	Name         string       `yaml:"name"`
+	Value        float64      `yaml:"value,omitempty"`
+	Observations Observations `yaml:"observations,omitempty"`
+	Samples      int          `yaml:"samples,omitempty"`
+	Dist         string       `yaml:"dist,omitempty"`
+	Seasonal     *Seasonal    `yaml:"seasonal,omitempty"`
+	Exemplar     *Exemplar    `yaml:"exemplar,omitempty"`
+}
+
+// Observations are histogram observations. Unlike nil, an empty list
+// means record nothing in that collect period.
+type Observations []float64
+
+// IsZero makes omitempty omit only nil Observations.
+func (o Observations) IsZero() bool {
+	return o == nil
+}
+
+// Exemplar gives the trace and span that a sum or histogram value is
+// recorded in. The IDs are hex encoded: 32 digits for the trace ID and
+// 16 digits for the span ID.
+type Exemplar struct {
+	TraceID string `yaml:"traceId"`
+	SpanID  string `yaml:"spanId"`
 }
 
 // MetricValueSet is a set of values to send for each metric. If the value
@@ -42,10 +99,32 @@ type MetricValueSet struct {
 	ValueSet []MetricValue `yaml:"valueSet"`
 }
 
+// ServiceInfo describes a virtual service simulated by the tester. Each
+// replica of the service gets its own resource and exporter but all
+// replicas send the same metric values.
+type ServiceInfo struct {
+
+	// The service.name resource attribute.
+	Name string `yaml:"name"`
+
+	// The number of instances of this service. Each instance has a
+	// service.instance.id resource attribute of the form name-N where N
+	// starts at 0. Default is 1.
+	Replicas int `yaml:"replicas,omitempty"`
+
+	// Additional resource attributes for each instance of this service.
+	// They may not set service.name or service.instance.id.
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
 // Config represents the yaml configuration file which controls the metrics
 // sent to the OTEL collector.
 type Config struct {
 
+	// Config files or directories to merge before this one, relative to
+	// this file. See ReadConfigFromFiles.
+	Include []string `yaml:"include,omitempty"`
+
 	// Controls how often metric values get sent. If set to '10s', metrics
 	// get sent every 10 seconds. Default is 10s.
 	CollectPeriod time.Duration `yaml:"collectPeriod"`
@@ -54,6 +133,24 @@ type Config struct {
 	// 'localhost:4317'
 	AggregationTemporalitySelector string `yaml:"aggregationTemporalitySelector"`
 
+	// The prefix added to the names of sums and histograms when they are
+	// sent. If omitted, the prefix is 'cum_' or 'delta_' depending on
+	// the temporality of each metric. Set to "" for no prefix.
+	NamePrefix *string `yaml:"namePrefix,omitempty"`
+
+	// The name of the meter that records the metrics, which becomes their
+	// instrumentation scope. Default is 'opamp'.
+	Meter string `yaml:"meter,omitempty"`
+
+	// The virtual services sending the metrics. If empty, the metrics are
+	// sent from a single default service.
+	Services []ServiceInfo `yaml:"services,omitempty"`
+
+	// If true, each sum and histogram value without an exemplar in its
+	// MetricValue is recorded in a new sampled span context with random
+	// IDs.
+	GenerateExemplars bool `yaml:"generateExemplars,omitempty"`
+
 	// The names and types of the metrics being sent.
 	Metrics []MetricInfo `yaml:"metrics"`
 
@@ -61,31 +158,71 @@ type Config struct {
 	// or whatever CollectPeriod is set to. After the last ValueSet is sent
 	// it loops back to the first.
 	ValueSets []MetricValueSet `yaml:"valueSets"`
+
+	// A JSON Lines or CSV file of value sets to play instead of
+	// ValueSets. The file is read lazily so it can be larger than memory.
+	// A relative path is relative to the config file. See ValueSetStream.
+	ValueSource string `yaml:"valueSource,omitempty"`
+
+	// The traces to be sent. Each scenario is sent at its own rate.
+	Traces []TraceScenario `yaml:"traces,omitempty"`
+
+	// The log records to be sent. Each stream is sent at its own rate.
+	Logs []LogStream `yaml:"logs,omitempty"`
+
+	// The phases of the scenario, played in order and then from the
+	// beginning again. If empty, ValueSets are played at CollectPeriod.
+	Phases []Phase `yaml:"phases,omitempty"`
+
+	// Views that change how instruments are aggregated and exported.
+	// Optional.
+	Views []View `yaml:"views,omitempty"`
+
+	// Changes to the values of metrics at scheduled times. Optional.
+	Anomalies []Anomaly `yaml:"anomalies,omitempty"`
+
+	// Pathological data to mix into the metrics sent. Optional.
+	Faults *Faults `yaml:"faults,omitempty"`
+
+	// Conflicts found while merging config files.
+	conflicts []error
 }
 
-// ReadConfig reads the yaml config file from reader r.
+// ReadConfig reads the yaml config file from reader r. Included files
+// are relative to the current directory, and placeholders are replaced
+// from environment variables.
 func ReadConfig(r io.Reader) (*Config, error) {
-	decoder := yaml.NewDecoder(r)
-	decoder.SetStrict(true)
-	var result Config
-	if err := decoder.Decode(&result); err != nil {
+	l := newConfigLoader(nil)
+	if err := l.load(r, "config", "."); err != nil {
 		return nil, err
 	}
-	result.fixDefaults()
-	if err := checkConfig(&result); err != nil {
-		return nil, err
+	return l.finish()
+}
+
+// WriteConfig writes config to w as yaml.
+func WriteConfig(w io.Writer, config *Config) error {
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(config); err != nil {
+		return err
 	}
-	return &result, nil
+	return encoder.Close()
 }
 
-// ReadConfigFromFile reads the yaml config file from disk.
+// ReadConfigFromFile reads the yaml config file from disk along with the
+// files it includes. fileName may also be a directory of config files.
 func ReadConfigFromFile(fileName string) (*Config, error) {
-	f, err := os.Open(fileName)
+	return ReadConfigFromFiles(fileName)
+}
+
+// Hash returns a hex encoded SHA-256 hash of this config. Two configs that
+// differ only in formatting or comments have the same hash.
+func (c *Config) Hash() (string, error) {
+	data, err := yaml.Marshal(c)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	defer f.Close()
-	return ReadConfig(f)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 func (c *Config) fixDefaults() {
@@ -95,19 +232,70 @@ func (c *Config) fixDefaults() {
 	if c.AggregationTemporalitySelector == "" {
 		c.AggregationTemporalitySelector = CumulativeAggregationSelector
 	}
+	if c.Meter == "" {
+		c.Meter = "opamp"
+	}
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		if m.Temporality == "" && m.Type != MetricTypeGauge {
+			m.Temporality = c.AggregationTemporalitySelector
+		}
+	}
+	for i := range c.Services {
+		if c.Services[i].Replicas == 0 {
+			c.Services[i].Replicas = 1
+		}
+	}
+	for i := range c.Traces {
+		c.Traces[i].Root.fixDefaults()
+	}
+	for i := range c.Logs {
+		if c.Logs[i].Severity == "" {
+			c.Logs[i].Severity = "INFO"
+		}
+	}
+	for i := range c.Phases {
+		if c.Phases[i].CollectPeriod == 0 {
+			c.Phases[i].CollectPeriod = c.CollectPeriod
+		}
+	}
+	if c.Faults != nil {
+		c.Faults.fixDefaults()
+	}
+	for i := range c.Views {
+		if c.Views[i].Aggregation != nil {
+			c.Views[i].Aggregation.fixDefaults()
+		}
+	}
+}
+
+// InstrumentName returns the name under which metric m is sent.
+func (c *Config) InstrumentName(m MetricInfo) string {
+	name := m.Name
+	if m.Instrument != "" {
+		name = m.Instrument
+	}
+	if m.Type == MetricTypeGauge {
+		return name
+	}
+	if c.NamePrefix != nil {
+		return *c.NamePrefix + name
+	}
+	if m.Temporality == DeltaAggregationSelector {
+		return "delta_" + name
+	}
+	return "cum_" + name
 }
 
 // Engine instances keeps track of the metric values. It plays back
 // the metric values in the yaml file.
 type Engine struct {
 
-	// The values for the metrics are stored here. This map never changes.
-	// The key is composite. The first part of the key is the metric name;
-	// the second part of the key is an integer indicating the
-	// MetricValueSet. 0 means the first; 1 means the second etc. The key
-	// has to be composite because the value for a particular metric
-	// changes between MetricValueSets.
-	values map[stringInt]float64
+	// The values of each metric by name. This map never changes. Each
+	// metric only stores the MetricValueSets where its value changes, so
+	// memory grows with the number of values in the yaml file rather than
+	// with metrics times MetricValueSets.
+	series map[string]*engineSeries
 
 	// This is the total number of MetricValueSets and it never changes.
 	indexCount int
@@ -115,48 +303,384 @@ type Engine struct {
 	// lock protects the fields below.
 	lock sync.Mutex
 
-	// For each metric name, indexes gives the 0 based MetricValueSet
-	// with the next value for that metric.  This field changes with each
-	// call to NextValue. The value for each metric name will be between
-	// 0 and indexCount - 1 inclusive.
-	indexes map[string]int
+	// rand generates observations for histogram distributions and noise
+	// for seasonal values. It is seeded the same way every time so that
+	// runs can be repeated.
+	rand *rand.Rand
+
+	// When this Engine was created. Seasonal values start from here.
+	start time.Time
+}
+
+// engineSeries holds the values of one metric.
+type engineSeries struct {
+
+	// calls is the number of values handed out so far.
+	calls atomic.Uint64
+
+	// changes are the 0 based MetricValueSets that give a value for the
+	// metric in increasing order. values[i] is the value from changes[i]
+	// until the next change. Most values are plain numbers, so values
+	// holds just the number and rich holds the whole value, by index in
+	// changes, for those with observations, distributions, seasonal
+	// values or exemplars. rich is nil if there are none. These never
+	// change.
+	changes []int
+	values  []float64
+	rich    map[int]*engineValue
+
+	// The value before the first change, if not the zero value. See
+	// Engine.CarryFrom.
+	carried atomic.Pointer[engineValue]
+}
+
+// set sets the value from changes[i] to v.
+func (s *engineSeries) set(i int, v engineValue) {
+	s.values[i] = v.value
+	if v.plain() {
+		delete(s.rich, i)
+		return
+	}
+	if s.rich == nil {
+		s.rich = make(map[int]*engineValue)
+	}
+	rich := v
+	s.rich[i] = &rich
+}
+
+// Handle plays back the values of one metric in an Engine. Handles are
+// safe to use with multiple goroutines and don't take locks, except to
+// draw random numbers for distributions and seasonal noise.
+type Handle struct {
+	engine *Engine
+	series *engineSeries
+}
+
+// Handle returns the Handle for the metric with the given name. A metric
+// with no values in the Engine always gives the zero value.
+func (e *Engine) Handle(name string) *Handle {
+	return &Handle{engine: e, series: e.series[name]}
+}
+
+// Next returns the next value of this handle's metric. Like
+// Engine.NextValue, each call gives the value in the next MetricValueSet.
+func (h *Handle) Next() float64 {
+	return h.engine.value(h.engine.next(h.series))
+}
+
+// Peek returns the value that Next would return without advancing.
+// Seasonal values are computed afresh on each call.
+func (h *Handle) Peek() float64 {
+	return h.engine.peek(h.series)
+}
+
+// NextSample returns everything in the next MetricValueSet for this
+// handle's metric.
+func (h *Handle) NextSample() Sample {
+	return h.engine.sample(h.engine.next(h.series))
+}
+
+// next returns the next value of s and advances s. s may be nil.
+func (e *Engine) next(s *engineSeries) engineValue {
+	if s == nil {
+		return engineValue{}
+	}
+	n := s.calls.Add(1) - 1
+	return s.valueAt(int(n % uint64(e.indexCount)))
+}
+
+// peek returns the next value of s without advancing. s may be nil.
+func (e *Engine) peek(s *engineSeries) float64 {
+	if s == nil {
+		return 0
+	}
+	return e.value(s.valueAt(e.position(s)))
+}
+
+// valueAt returns the value of this metric in the MetricValueSet at idx.
+func (s *engineSeries) valueAt(idx int) engineValue {
+	// The last change at or before idx.
+	pos := sort.SearchInts(s.changes, idx+1) - 1
+	if pos >= 0 {
+		if rich, ok := s.rich[pos]; ok {
+			return *rich
+		}
+		return engineValue{value: s.values[pos]}
+	}
+	if carried := s.carried.Load(); carried != nil {
+		return *carried
+	}
+	return engineValue{}
+}
+
+// position returns the index of the next MetricValueSet of s.
+func (e *Engine) position(s *engineSeries) int {
+	return int(s.calls.Load() % uint64(e.indexCount))
+}
+
+// EngineSnapshot records where an Engine is in its MetricValueSets: the 0
+// based index of the next MetricValueSet of each metric by name.
+type EngineSnapshot map[string]int
+
+// Peek returns the value that NextValue would return for the given metric
+// name without advancing.
+func (e *Engine) Peek(name string) float64 {
+	return e.peek(e.series[name])
+}
+
+// Seek makes every metric continue from the MetricValueSet at the given 0
+// based index. Indexes past the last MetricValueSet wrap around.
+func (e *Engine) Seek(index int) {
+	if e.indexCount == 0 || index < 0 {
+		return
+	}
+	for _, s := range e.series {
+		s.calls.Store(uint64(index % e.indexCount))
+	}
+}
+
+// Reset makes every metric start again from the first MetricValueSet.
+func (e *Engine) Reset() {
+	e.Seek(0)
+}
+
+// CarryFrom makes the metrics of this Engine start with the values they
+// had in prev after prev played its first played MetricValueSets, looping
+// if need be. A metric keeps that value until a MetricValueSet of this
+// Engine gives it a new one. If played is 0, metrics start with the
+// values they started with in prev. Only metrics this Engine has values
+// for, or was given with AddMetrics, are carried.
+func (e *Engine) CarryFrom(prev *Engine, played int) {
+	if prev.indexCount == 0 {
+		return
+	}
+	for name, s := range e.series {
+		ps, ok := prev.series[name]
+		if !ok {
+			continue
+		}
+		var value engineValue
+		if played > 0 {
+			value = ps.valueAt((played - 1) % prev.indexCount)
+		} else {
+			value = ps.valueAt(-1)
+		}
+		s.carried.Store(&value)
+	}
+}
+
+// AddMetrics makes this Engine keep track of the metrics with the given
+// names even if none of its MetricValueSets gives them a value, so that
+// CarryFrom carries their values.
+func (e *Engine) AddMetrics(names ...string) {
+	if e.indexCount == 0 {
+		return
+	}
+	for _, name := range names {
+		if _, ok := e.series[name]; !ok {
+			e.series[name] = &engineSeries{}
+		}
+	}
+}
+
+// Snapshot returns where this Engine is in its MetricValueSets. It does
+// not include the state of the random numbers behind distributions and
+// seasonal noise.
+func (e *Engine) Snapshot() EngineSnapshot {
+	result := make(EngineSnapshot, len(e.series))
+	for name, s := range e.series {
+		result[name] = e.position(s)
+	}
+	return result
+}
+
+// Restore moves this Engine back to where it was when snapshot was taken.
+// Metrics missing from snapshot are left alone.
+func (e *Engine) Restore(snapshot EngineSnapshot) error {
+	for name, index := range snapshot {
+		s, ok := e.series[name]
+		if !ok {
+			continue
+		}
+		if index < 0 || index >= e.indexCount {
+			return fmt.Errorf(
+				"Snapshot index %d of metric '%s' out of range", index, name)
+		}
+		s.calls.Store(uint64(index))
+	}
+	return nil
+}
+
+// Sample is what the Engine plays back for one metric in one
+// MetricValueSet.
+type Sample struct {
+
+	// The value of a gauge or sum.
+	Value float64
+
+	// The observations to record for a histogram. If the MetricValue has
+	// only a value, this has just that value.
+	Observations []float64
+
+	// The exemplar to record with the value, if any.
+	Exemplar *Exemplar
+}
+
+// engineValue is the value of one metric in one MetricValueSet.
+type engineValue struct {
+	value        float64
+	observations []float64
+	dist         Distribution
+	samples      int
+	seasonal     *Seasonal
+	exemplar     *Exemplar
+}
+
+func newEngineValue(metricValue MetricValue) engineValue {
+	result := engineValue{
+		value:        metricValue.Value,
+		observations: metricValue.Observations,
+		samples:      metricValue.Samples,
+		seasonal:     metricValue.Seasonal,
+		exemplar:     metricValue.Exemplar,
+	}
+	if metricValue.Dist != "" {
+		// checkConfig already reported malformed distributions.
+		result.dist, _ = ParseDistribution(metricValue.Dist)
+	}
+	return result
+}
+
+// plain returns true if v is just a number.
+func (v *engineValue) plain() bool {
+	return v.observations == nil &&
+		v.dist == nil &&
+		v.samples == 0 &&
+		v.seasonal == nil &&
+		v.exemplar == nil
 }
 
 // NewEngine returns a new Engine from the MetricValueSets in the yaml
 // file.
 func NewEngine(valueSets []MetricValueSet) *Engine {
-	valuesAtIndex := make(map[string]float64)
-	values := make(map[stringInt]float64)
+	// Counting the changes of each metric first lets all the series share
+	// a few large arrays rather than each growing its own.
+	ids := make(map[string]int)
+	var counts, last []int
+	total := 0
 	for idx, valueSet := range valueSets {
 		for _, metricValue := range valueSet.ValueSet {
-			valuesAtIndex[metricValue.Name] = metricValue.Value
+			id, ok := ids[metricValue.Name]
+			if !ok {
+				id = len(counts)
+				ids[metricValue.Name] = id
+				counts = append(counts, 0)
+				last = append(last, -1)
+			}
+			if last[id] != idx {
+				last[id] = idx
+				counts[id]++
+				total++
+			}
 		}
-		for k, v := range valuesAtIndex {
-			values[stringInt{name: k, idx: idx}] = v
+	}
+	all := make([]engineSeries, len(counts))
+	changes := make([]int, total)
+	values := make([]float64, total)
+	start := 0
+	for id, count := range counts {
+		end := start + count
+		all[id].changes = changes[start:start:end]
+		all[id].values = values[start:end:end]
+		start = end
+	}
+	for idx, valueSet := range valueSets {
+		for _, metricValue := range valueSet.ValueSet {
+			s := &all[ids[metricValue.Name]]
+			// A later value for the same metric in the same
+			// MetricValueSet wins.
+			n := len(s.changes)
+			if n == 0 || s.changes[n-1] != idx {
+				s.changes = append(s.changes, idx)
+				n++
+			}
+			s.set(n-1, newEngineValue(metricValue))
 		}
 	}
+	series := make(map[string]*engineSeries, len(ids))
+	for name, id := range ids {
+		series[name] = &all[id]
+	}
 	return &Engine{
-		values:     values,
+		series:     series,
 		indexCount: len(valueSets),
-		indexes:    make(map[string]int),
+		rand:       rand.New(rand.NewSource(1)),
+		start:      time.Now(),
 	}
 }
 
 // NextValue returns the next value for the given metric name. This method
 // is not idempotent. Each call to it gives the next value for that metric.
 func (e *Engine) NextValue(name string) float64 {
-	return e.values[stringInt{name: name, idx: e.getAndIncrementIndex(name)}]
+	return e.value(e.next(e.series[name]))
+}
+
+// NextObservations returns the next observations for the given histogram
+// name. Like NextValue, each call gives the observations in the next
+// MetricValueSet. If the MetricValue for name has only a value, the
+// result has just that value.
+func (e *Engine) NextObservations(name string) []float64 {
+	return e.NextSample(name).Observations
+}
+
+// NextSample returns everything in the next MetricValueSet for the given
+// metric name. Like NextValue, this method is not idempotent.
+func (e *Engine) NextSample(name string) Sample {
+	return e.sample(e.next(e.series[name]))
+}
+
+// value returns the value of v, computing it if it is seasonal.
+func (e *Engine) value(v engineValue) float64 {
+	if v.seasonal != nil {
+		return e.seasonalValue(v.seasonal)
+	}
+	return v.value
 }
 
-func (e *Engine) getAndIncrementIndex(name string) int {
+func (e *Engine) sample(v engineValue) Sample {
+	v.value = e.value(v)
+	result := Sample{Value: v.value, Exemplar: v.exemplar}
+	switch {
+	case v.dist != nil:
+		result.Observations = e.sampleDist(v.dist, v.samples)
+	case v.observations != nil:
+		result.Observations = v.observations
+	default:
+		result.Observations = []float64{v.value}
+	}
+	return result
+}
+
+func (e *Engine) sampleDist(dist Distribution, samples int) []float64 {
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	result := e.indexes[name]
-	e.indexes[name] = (result + 1) % e.indexCount
+	result := make([]float64, samples)
+	for i := range result {
+		result[i] = dist.Sample(e.rand)
+	}
 	return result
 }
 
+func (e *Engine) seasonalValue(s *Seasonal) float64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return s.Value(e.start, time.Now(), e.rand)
+}
+
 func checkConfig(config *Config) error {
+	if len(config.conflicts) > 0 {
+		return config.conflicts[0]
+	}
 	if config.CollectPeriod <= 0 {
 		return errors.New("collectPeriod must be a positive duration")
 	}
@@ -166,30 +690,228 @@ func checkConfig(config *Config) error {
 		return errors.New("aggregationTemporalitySelector can be either delta or cumulative")
 	}
 
-	namesSeen := make(map[string]struct{})
+	if err := checkViews(config); err != nil {
+		return err
+	}
+
+	servicesSeen := make(map[string]struct{})
+	for _, service := range config.Services {
+		if service.Name == "" {
+			return errors.New("services must have a name")
+		}
+		if _, ok := servicesSeen[service.Name]; ok {
+			return fmt.Errorf("Duplicate service: %s", service.Name)
+		}
+		servicesSeen[service.Name] = struct{}{}
+		if service.Replicas < 0 {
+			return fmt.Errorf(
+				"Service '%s' has negative replicas", service.Name)
+		}
+		for _, key := range []string{"service.name", "service.instance.id"} {
+			if _, ok := service.Attributes[key]; ok {
+				return fmt.Errorf(
+					"Service '%s' cannot set %s in attributes",
+					service.Name,
+					key)
+			}
+		}
+	}
+
+	instruments := make(map[string]MetricInfo)
+	namesSeen := make(map[string]string)
 	for _, metric := range config.Metrics {
 		if _, ok := namesSeen[metric.Name]; ok {
 			return fmt.Errorf("Duplicate metric: %s", metric.Name)
 		}
-		namesSeen[metric.Name] = struct{}{}
+		namesSeen[metric.Name] = metric.Type
 		if !metricTypeNames[metric.Type] {
 			return fmt.Errorf("Unknown metric type: %s", metric.Type)
 		}
+		switch metric.Temporality {
+		case DeltaAggregationSelector, CumulativeAggregationSelector:
+			if metric.Type == MetricTypeGauge {
+				return fmt.Errorf(
+					"Gauge '%s' cannot have a temporality", metric.Name)
+			}
+		case "":
+		default:
+			return fmt.Errorf(
+				"Metric '%s' temporality can be either delta or cumulative",
+				metric.Name)
+		}
+		if err := checkBoundaries(metric); err != nil {
+			return err
+		}
+		if err := CheckUnit(metric.Unit); err != nil {
+			return fmt.Errorf("Metric '%s': %v", metric.Name, err)
+		}
+		if err := checkViewAggregation(config, metric); err != nil {
+			return err
+		}
+		name := config.ExportedName(metric)
+		if other, ok := instruments[name]; ok && !sameInstrument(metric, other) {
+			return fmt.Errorf(
+				"Metrics '%s' and '%s' share instrument '%s' but differ in type, temporality, boundaries, unit, or description",
+				other.Name,
+				metric.Name,
+				name)
+		}
+		instruments[name] = metric
+	}
+	if err := checkValueSets(config.ValueSets, namesSeen); err != nil {
+		return err
+	}
+	if config.ValueSource != "" &&
+		(len(config.ValueSets) > 0 || len(config.Phases) > 0) {
+		return errors.New(
+			"valueSource cannot be combined with valueSets or phases")
+	}
+	if err := checkPhases(config, namesSeen); err != nil {
+		return err
+	}
+	if err := checkAnomalies(config, namesSeen); err != nil {
+		return err
+	}
+	if err := checkTraces(config); err != nil {
+		return err
+	}
+	if err := checkLogs(config); err != nil {
+		return err
 	}
-	for _, valueSet := range config.ValueSets {
+	return checkFaults(config)
+}
+
+// checkValueSets checks valueSets given the type of each metric by name.
+func checkValueSets(
+	valueSets []MetricValueSet, metricTypes map[string]string) error {
+	for _, valueSet := range valueSets {
 		for _, metricValue := range valueSet.ValueSet {
-			if _, ok := namesSeen[metricValue.Name]; !ok {
+			metricType, ok := metricTypes[metricValue.Name]
+			if !ok {
 				return fmt.Errorf(
 					"Unknown metric name '%s' in values section",
 					metricValue.Name,
 				)
 			}
+			if err := checkMetricValue(metricValue, metricType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkBoundaries(metric MetricInfo) error {
+	if metric.Boundaries == nil {
+		return nil
+	}
+	if metric.Type != MetricTypeHistogram {
+		return fmt.Errorf(
+			"Only histograms can have boundaries: %s", metric.Name)
+	}
+	for i := 1; i < len(metric.Boundaries); i++ {
+		if metric.Boundaries[i] <= metric.Boundaries[i-1] {
+			return fmt.Errorf(
+				"Histogram '%s' boundaries must be increasing", metric.Name)
 		}
 	}
 	return nil
 }
 
-type stringInt struct {
-	name string
-	idx  int
+func sameInstrument(m1, m2 MetricInfo) bool {
+	if m1.Type != m2.Type || m1.Temporality != m2.Temporality {
+		return false
+	}
+	if m1.Unit != m2.Unit || m1.Description != m2.Description {
+		return false
+	}
+	if len(m1.Boundaries) != len(m2.Boundaries) {
+		return false
+	}
+	for i := range m1.Boundaries {
+		if m1.Boundaries[i] != m2.Boundaries[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func checkMetricValue(metricValue MetricValue, metricType string) error {
+	if metricValue.Exemplar != nil {
+		if metricType == MetricTypeGauge {
+			return fmt.Errorf(
+				"Gauge '%s' cannot have an exemplar", metricValue.Name)
+		}
+		if err := checkExemplar(metricValue.Exemplar); err != nil {
+			return fmt.Errorf("Metric '%s': %v", metricValue.Name, err)
+		}
+	}
+	hasObservations := metricValue.Observations != nil
+	hasDist := metricValue.Dist != ""
+	if metricValue.Seasonal != nil {
+		if metricValue.Value != 0 || hasObservations || hasDist {
+			return fmt.Errorf(
+				"Metric '%s' can have only one of value, observations, dist, or seasonal",
+				metricValue.Name)
+		}
+		if err := checkSeasonal(metricValue.Seasonal); err != nil {
+			return fmt.Errorf("Metric '%s': %v", metricValue.Name, err)
+		}
+	}
+	if !hasDist && metricValue.Samples != 0 {
+		return fmt.Errorf(
+			"Metric '%s' has samples but no dist", metricValue.Name)
+	}
+	if !hasObservations && !hasDist {
+		return nil
+	}
+	if metricType != MetricTypeHistogram {
+		return fmt.Errorf(
+			"Only histograms can have observations or dist: %s",
+			metricValue.Name)
+	}
+	if metricValue.Value != 0 || (hasObservations && hasDist) {
+		return fmt.Errorf(
+			"Metric '%s' can have only one of value, observations, or dist",
+			metricValue.Name)
+	}
+	if !hasDist {
+		return nil
+	}
+	if metricValue.Samples <= 0 {
+		return fmt.Errorf(
+			"Metric '%s' needs a positive number of samples",
+			metricValue.Name)
+	}
+	if metricValue.Samples > MaxSamples {
+		return fmt.Errorf(
+			"Metric '%s' can have at most %d samples",
+			metricValue.Name,
+			MaxSamples)
+	}
+	if _, err := ParseDistribution(metricValue.Dist); err != nil {
+		return fmt.Errorf("Metric '%s': %v", metricValue.Name, err)
+	}
+	return nil
+}
+
+func checkExemplar(exemplar *Exemplar) error {
+	traceID, err := hex.DecodeString(exemplar.TraceID)
+	if err != nil || len(traceID) != 16 || isZero(traceID) {
+		return fmt.Errorf("invalid exemplar traceId: %s", exemplar.TraceID)
+	}
+	spanID, err := hex.DecodeString(exemplar.SpanID)
+	if err != nil || len(spanID) != 8 || isZero(spanID) {
+		return fmt.Errorf("invalid exemplar spanId: %s", exemplar.SpanID)
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,407 @@
+package gooteltest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	benchmarkMetrics   = 100000
+	benchmarkValueSets = 10000
+
+	// Each value set changes this many metrics, so that every metric
+	// changes benchmarkValueSets * benchmarkChanges / benchmarkMetrics
+	// times.
+	benchmarkChanges = 100
+)
+
+var (
+	benchmarkOnce  sync.Once
+	benchmarkNames []string
+	benchmarkSets  []MetricValueSet
+)
+
+// benchmarkData returns the metric names and value sets of the
+// benchmarks, made on first use.
+func benchmarkData() ([]string, []MetricValueSet) {
+	benchmarkOnce.Do(func() {
+		benchmarkNames = make([]string, benchmarkMetrics)
+		for i := range benchmarkNames {
+			benchmarkNames[i] = fmt.Sprintf("metric.%d", i)
+		}
+		benchmarkSets = make([]MetricValueSet, benchmarkValueSets)
+		next := 0
+		for i := range benchmarkSets {
+			values := make([]MetricValue, benchmarkChanges)
+			for j := range values {
+				values[j] = MetricValue{
+					Name:  benchmarkNames[next],
+					Value: float64(i),
+				}
+				next = (next + 1) % benchmarkMetrics
+			}
+			benchmarkSets[i].ValueSet = values
+		}
+	})
+	return benchmarkNames, benchmarkSets
+}
+
+func TestWriteConfigServices(t *testing.T) {
+	const metrics = `
+metrics:
+- name: "temp"
+  type: "gauge"
+valueSets:
+- valueSet:
+  - name: "temp"
+    value: 1
+`
+	for _, tc := range []struct {
+		name     string
+		services string
+		want     []string
+		wantNot  []string
+	}{
+		{name: "none", wantNot: []string{"services:"}},
+		{
+			name:     "noAttributes",
+			services: "services:\n- name: \"svc\"\n",
+			want:     []string{"- name: svc\n  replicas: 1\n"},
+			wantNot:  []string{"attributes:"},
+		},
+		{
+			name: "attributes",
+			services: "services:\n" +
+				"- {name: \"svc\", attributes: {region: \"east\"}}\n",
+			want: []string{"  attributes:\n    region: east\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := ReadConfig(strings.NewReader(metrics + tc.services))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := WriteConfig(&b, config); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("Got %s, want %q", b.String(), want)
+				}
+			}
+			for _, wantNot := range tc.wantNot {
+				if strings.Contains(b.String(), wantNot) {
+					t.Errorf("Got %s, want no %q", b.String(), wantNot)
+				}
+			}
+			// What is written reads back the same.
+			again, err := ReadConfig(strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again.Services, config.Services) {
+				t.Errorf("Got services %+v, want %+v",
+					again.Services, config.Services)
+			}
+		})
+	}
+}
+
+func TestReadConfigServiceAttributes(t *testing.T) {
+	for _, key := range []string{"service.name", "service.instance.id"} {
+		_, err := ReadConfig(strings.NewReader(`
+services:
+- name: "svc"
+  attributes:
+    region: "east"
+    ` + key + `: "other"
+metrics:
+- name: "temp"
+  type: "gauge"
+valueSets:
+- valueSet:
+  - name: "temp"
+    value: 1
+`))
+		want := "Service 'svc' cannot set " + key + " in attributes"
+		if err == nil || err.Error() != want {
+			t.Errorf("Got error %v, want %s", err, want)
+		}
+	}
+}
+
+// testValueSets gives 'a' the values 1, 2, 3, 4 and 'b' the values 10,
+// 10, 30, 30.
+var testValueSets = []MetricValueSet{
+	{ValueSet: []MetricValue{{Name: "a", Value: 1}, {Name: "b", Value: 10}}},
+	{ValueSet: []MetricValue{{Name: "a", Value: 2}}},
+	{ValueSet: []MetricValue{{Name: "a", Value: 3}, {Name: "b", Value: 30}}},
+	{ValueSet: []MetricValue{{Name: "a", Value: 4}}},
+}
+
+// assertNextValues checks the next values of metric name in engine.
+func assertNextValues(
+	t *testing.T, engine *Engine, name string, want ...float64) {
+	t.Helper()
+	for i, w := range want {
+		if got := engine.NextValue(name); got != w {
+			t.Errorf("Value %d of %s: got %v, want %v", i, name, got, w)
+		}
+	}
+}
+
+func TestEnginePeek(t *testing.T) {
+	engine := NewEngine(testValueSets)
+	for i, want := range []float64{1, 2, 3, 4, 1} {
+		// Peeking twice gives the same value and does not advance.
+		for j := 0; j < 2; j++ {
+			if got := engine.Peek("a"); got != want {
+				t.Errorf("Peek %d: got %v, want %v", i, got, want)
+			}
+		}
+		assertNextValues(t, engine, "a", want)
+	}
+	if got := engine.Peek("missing"); got != 0 {
+		t.Errorf("Got %v for a missing metric, want 0", got)
+	}
+}
+
+func TestEngineSeek(t *testing.T) {
+	for _, tc := range []struct {
+		index int
+		a     []float64
+		b     []float64
+	}{
+		{index: 0, a: []float64{1, 2}, b: []float64{10, 10}},
+		{index: 2, a: []float64{3, 4, 1}, b: []float64{30, 30, 10}},
+		{index: 5, a: []float64{2, 3}, b: []float64{10, 30}},
+		{index: 8, a: []float64{1}, b: []float64{10}},
+	} {
+		t.Run(fmt.Sprint(tc.index), func(t *testing.T) {
+			engine := NewEngine(testValueSets)
+			engine.NextValue("a")
+			engine.Seek(tc.index)
+			assertNextValues(t, engine, "a", tc.a...)
+			assertNextValues(t, engine, "b", tc.b...)
+		})
+	}
+}
+
+func TestEngineReset(t *testing.T) {
+	engine := NewEngine(testValueSets)
+	assertNextValues(t, engine, "a", 1, 2, 3)
+	engine.Reset()
+	assertNextValues(t, engine, "a", 1)
+	assertNextValues(t, engine, "b", 10)
+}
+
+func TestEngineSnapshotRestore(t *testing.T) {
+	engine := NewEngine(testValueSets)
+	assertNextValues(t, engine, "a", 1, 2, 3)
+	assertNextValues(t, engine, "b", 10)
+	snapshot := engine.Snapshot()
+	want := EngineSnapshot{"a": 3, "b": 1}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("Got %v, want %v", snapshot, want)
+	}
+	engine.Reset()
+
+	// A new engine restores the same way as the one the snapshot came
+	// from.
+	for _, e := range []*Engine{engine, NewEngine(testValueSets)} {
+		if err := e.Restore(snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Snapshot(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Got %v after Restore, want %v", got, want)
+		}
+		assertNextValues(t, e, "a", 4, 1)
+		assertNextValues(t, e, "b", 10, 30)
+	}
+}
+
+func TestEngineRestoreOutOfRange(t *testing.T) {
+	for _, index := range []int{-1, 4} {
+		engine := NewEngine(testValueSets)
+		err := engine.Restore(EngineSnapshot{"a": index})
+		want := fmt.Sprintf(
+			"Snapshot index %d of metric 'a' out of range", index)
+		if err == nil || err.Error() != want {
+			t.Errorf("Got error %v, want %s", err, want)
+		}
+	}
+	// Metrics the engine doesn't have are left alone.
+	engine := NewEngine(testValueSets)
+	if err := engine.Restore(EngineSnapshot{"missing": 99}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEngineCarryForward(t *testing.T) {
+	valueSets := []MetricValueSet{
+		{ValueSet: []MetricValue{{Name: "a", Value: 1}}},
+		{ValueSet: []MetricValue{
+			{Name: "b", Value: 5},
+			{Name: "h", Observations: []float64{1, 2}},
+		}},
+		{ValueSet: []MetricValue{{Name: "a", Value: 2}}},
+		{ValueSet: []MetricValue{
+			{Name: "h", Observations: []float64{3}},
+			// The later value wins.
+			{Name: "h", Value: 4},
+		}},
+	}
+	engine := NewEngine(valueSets)
+	// Before its first value, and again after wrapping around, a metric
+	// has the zero value.
+	assertNextValues(t, engine, "a", 1, 1, 2, 2, 1, 1)
+	assertNextValues(t, engine, "b", 0, 5, 5, 5, 0, 5)
+	for i, want := range [][]float64{{0}, {1, 2}, {1, 2}, {4}, {0}, {1, 2}} {
+		got := engine.NextSample("h").Observations
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Observations %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	// Carrying from itself, as when a config with one phase loops, makes
+	// the first MetricValueSet continue from the last.
+	engine.Reset()
+	engine.CarryFrom(engine, len(valueSets))
+	assertNextValues(t, engine, "a", 1, 1, 2, 2, 1)
+	assertNextValues(t, engine, "b", 5, 5, 5, 5, 5)
+	if got := engine.NextSample("h").Observations; !reflect.DeepEqual(
+		got, []float64{4}) {
+		t.Errorf("Got carried observations %v, want [4]", got)
+	}
+}
+
+// TestHandleNextConcurrent is meant to be run with -race.
+func TestHandleNextConcurrent(t *testing.T) {
+	const (
+		goroutines = 8
+		calls      = 1000
+	)
+	engine := NewEngine(testValueSets)
+	handle := engine.Handle("a")
+	counts := make([]map[float64]int, goroutines)
+	var wg sync.WaitGroup
+	for g := range counts {
+		counts[g] = make(map[float64]int)
+		wg.Add(1)
+		go func(counts map[float64]int) {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				counts[handle.Next()]++
+			}
+		}(counts[g])
+	}
+	wg.Wait()
+	// Every MetricValueSet is handed out the same number of times.
+	total := make(map[float64]int)
+	for _, c := range counts {
+		for value, n := range c {
+			total[value] += n
+		}
+	}
+	each := goroutines * calls / len(testValueSets)
+	want := map[float64]int{1: each, 2: each, 3: each, 4: each}
+	if !reflect.DeepEqual(total, want) {
+		t.Errorf("Got %v, want %v", total, want)
+	}
+	if got := engine.Snapshot()["a"]; got != 0 {
+		t.Errorf("Got position %d, want 0", got)
+	}
+}
+
+// mutexEngine plays back values the way Engine did before Handles: a
+// lock around a map of the next index of each metric. It is the baseline
+// for the parallel benchmarks.
+type mutexEngine struct {
+	engine  *Engine
+	lock    sync.Mutex
+	indexes map[string]int
+}
+
+func (e *mutexEngine) NextValue(name string) float64 {
+	e.lock.Lock()
+	idx := e.indexes[name]
+	e.indexes[name] = (idx + 1) % e.engine.indexCount
+	e.lock.Unlock()
+	s := e.engine.series[name]
+	if s == nil {
+		return 0
+	}
+	return e.engine.value(s.valueAt(idx))
+}
+
+func BenchmarkNewEngine(b *testing.B) {
+	_, valueSets := benchmarkData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewEngine(valueSets)
+	}
+}
+
+func BenchmarkEngineNextValue(b *testing.B) {
+	names, valueSets := benchmarkData()
+	engine := NewEngine(valueSets)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.NextValue(names[i%len(names)])
+	}
+}
+
+func BenchmarkEngineNextValueParallel(b *testing.B) {
+	names, valueSets := benchmarkData()
+	engine := NewEngine(valueSets)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			engine.NextValue(names[i%len(names)])
+			i++
+		}
+	})
+}
+
+func BenchmarkMutexEngineNextValueParallel(b *testing.B) {
+	names, valueSets := benchmarkData()
+	engine := &mutexEngine{
+		engine:  NewEngine(valueSets),
+		indexes: make(map[string]int),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			engine.NextValue(names[i%len(names)])
+			i++
+		}
+	})
+}
+
+func BenchmarkHandleNextParallel(b *testing.B) {
+	names, valueSets := benchmarkData()
+	engine := NewEngine(valueSets)
+	handles := make([]*Handle, len(names))
+	for i, name := range names {
+		handles[i] = engine.Handle(name)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			handles[i%len(handles)].Next()
+			i++
+		}
+	})
+}
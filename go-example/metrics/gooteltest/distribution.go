@@ -0,0 +1,102 @@
+package gooteltest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// MaxSamples is the most observations that one MetricValue may draw from
+// its distribution. Each value set draws them again, so more would stall
+// the run and the memory of the SDK.
+const MaxSamples = 100000
+
+// Distribution is a random distribution of histogram observations.
+type Distribution interface {
+
+	// Sample returns one random observation using r.
+	Sample(r *rand.Rand) float64
+}
+
+type normalDist struct{ mean, stddev float64 }
+
+func (d normalDist) Sample(r *rand.Rand) float64 {
+	return r.NormFloat64()*d.stddev + d.mean
+}
+
+type logNormalDist struct{ mu, sigma float64 }
+
+func (d logNormalDist) Sample(r *rand.Rand) float64 {
+	return math.Exp(r.NormFloat64()*d.sigma + d.mu)
+}
+
+type uniformDist struct{ min, max float64 }
+
+func (d uniformDist) Sample(r *rand.Rand) float64 {
+	return d.min + r.Float64()*(d.max-d.min)
+}
+
+type exponentialDist struct{ rate float64 }
+
+func (d exponentialDist) Sample(r *rand.Rand) float64 {
+	return r.ExpFloat64() / d.rate
+}
+
+// ParseDistribution parses a distribution spec. The supported specs are
+// 'normal(mean, stddev)', 'lognormal(mu, sigma)', 'uniform(min, max)', and
+// 'exponential(rate)'.
+func ParseDistribution(spec string) (Distribution, error) {
+	name, args, err := parseCall(spec)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "normal":
+		if len(args) != 2 || args[1] < 0 {
+			return nil, fmt.Errorf("normal needs a mean and a non-negative stddev: %s", spec)
+		}
+		return normalDist{mean: args[0], stddev: args[1]}, nil
+	case "lognormal":
+		if len(args) != 2 || args[1] < 0 {
+			return nil, fmt.Errorf("lognormal needs a mu and a non-negative sigma: %s", spec)
+		}
+		return logNormalDist{mu: args[0], sigma: args[1]}, nil
+	case "uniform":
+		if len(args) != 2 || args[0] > args[1] {
+			return nil, fmt.Errorf("uniform needs a min and a max: %s", spec)
+		}
+		return uniformDist{min: args[0], max: args[1]}, nil
+	case "exponential":
+		if len(args) != 1 || args[0] <= 0 {
+			return nil, fmt.Errorf("exponential needs a positive rate: %s", spec)
+		}
+		return exponentialDist{rate: args[0]}, nil
+	default:
+		return nil, fmt.Errorf("Unknown distribution: %s", spec)
+	}
+}
+
+// parseCall parses strings of the form 'name(1.0, 2.0)'.
+func parseCall(spec string) (string, []float64, error) {
+	spec = strings.TrimSpace(spec)
+	open := strings.IndexByte(spec, '(')
+	if open == -1 || !strings.HasSuffix(spec, ")") {
+		return "", nil, fmt.Errorf("Malformed distribution: %s", spec)
+	}
+	name := strings.TrimSpace(spec[:open])
+	var args []float64
+	for _, field := range strings.Split(spec[open+1:len(spec)-1], ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		arg, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("Malformed distribution: %s", spec)
+		}
+		args = append(args, arg)
+	}
+	return name, args, nil
+}
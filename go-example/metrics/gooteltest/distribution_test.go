@@ -0,0 +1,167 @@
+package gooteltest
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseDistribution(t *testing.T) {
+	for _, tc := range []struct {
+		spec string
+		want Distribution
+		err  string
+	}{
+		{spec: "normal(5, 1)", want: normalDist{mean: 5, stddev: 1}},
+		{spec: " normal( -5,0 ) ", want: normalDist{mean: -5}},
+		{spec: "lognormal(3, 0.8)", want: logNormalDist{mu: 3, sigma: 0.8}},
+		{spec: "uniform(1, 1)", want: uniformDist{min: 1, max: 1}},
+		{spec: "uniform(-1, 2.5)", want: uniformDist{min: -1, max: 2.5}},
+		{spec: "exponential(0.5)", want: exponentialDist{rate: 0.5}},
+		{spec: "normal(5, -1)", err: "normal needs a mean"},
+		{spec: "normal(5)", err: "normal needs a mean"},
+		{spec: "lognormal(1, -1)", err: "lognormal needs a mu"},
+		{spec: "uniform(2, 1)", err: "uniform needs a min and a max"},
+		{spec: "exponential(0)", err: "exponential needs a positive rate"},
+		{spec: "exponential(1, 2)", err: "exponential needs a positive rate"},
+		{spec: "poisson(1)", err: "Unknown distribution"},
+		{spec: "normal 5, 1", err: "Malformed distribution"},
+		{spec: "normal(5, x)", err: "Malformed distribution"},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := ParseDistribution(tc.spec)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Got error %v, want %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// meanAndStddev returns the mean and standard deviation of n samples from
+// dist, drawn with a fixed seed, after applying f to each one.
+func meanAndStddev(
+	dist Distribution, n int, f func(float64) float64) (float64, float64) {
+	r := rand.New(rand.NewSource(1))
+	var sum, sumSquares float64
+	for i := 0; i < n; i++ {
+		x := f(dist.Sample(r))
+		sum += x
+		sumSquares += x * x
+	}
+	mean := sum / float64(n)
+	return mean, math.Sqrt(sumSquares/float64(n) - mean*mean)
+}
+
+func TestDistributionSamples(t *testing.T) {
+	identity := func(x float64) float64 { return x }
+	for _, tc := range []struct {
+		spec string
+		// Applied to each sample before taking the mean and stddev.
+		f                func(float64) float64
+		mean, stddev     float64
+		minimum, maximum float64
+	}{
+		{
+			spec: "normal(5, 2)", f: identity, mean: 5, stddev: 2,
+			minimum: math.Inf(-1), maximum: math.Inf(1),
+		},
+		{
+			// The log of a lognormal sample is normal.
+			spec: "lognormal(1, 0.5)", f: math.Log, mean: 1, stddev: 0.5,
+			minimum: math.Inf(-1), maximum: math.Inf(1),
+		},
+		{
+			spec: "uniform(2, 6)", f: identity, mean: 4, stddev: 4 / math.Sqrt(12),
+			minimum: 2, maximum: 6,
+		},
+		{
+			spec: "exponential(0.5)", f: identity, mean: 2, stddev: 2,
+			minimum: 0, maximum: math.Inf(1),
+		},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			dist, err := ParseDistribution(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mean, stddev := meanAndStddev(dist, 100000, tc.f)
+			if math.Abs(mean-tc.mean) > 0.02*tc.stddev+0.01 {
+				t.Errorf("Got mean %v, want %v", mean, tc.mean)
+			}
+			if math.Abs(stddev-tc.stddev) > 0.02*tc.stddev {
+				t.Errorf("Got stddev %v, want %v", stddev, tc.stddev)
+			}
+			r := rand.New(rand.NewSource(2))
+			for i := 0; i < 10000; i++ {
+				if x := dist.Sample(r); x < tc.minimum || x > tc.maximum {
+					t.Fatalf("Got %v, want between %v and %v",
+						x, tc.minimum, tc.maximum)
+				}
+			}
+		})
+	}
+}
+
+func TestEngineDistribution(t *testing.T) {
+	valueSets := []MetricValueSet{{ValueSet: []MetricValue{
+		{Name: "latency", Samples: 500, Dist: "uniform(1, 2)"},
+	}}}
+	first := NewEngine(valueSets).NextSample("latency").Observations
+	if len(first) != 500 {
+		t.Fatalf("Got %d observations, want 500", len(first))
+	}
+	// Engines draw from the same seed, so every run records the same
+	// observations.
+	second := NewEngine(valueSets).NextSample("latency").Observations
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Observation %d: got %v and %v", i, first[i], second[i])
+		}
+	}
+}
+
+func TestReadConfigSamples(t *testing.T) {
+	for _, tc := range []struct {
+		samples string
+		want    string
+	}{
+		{samples: "1"},
+		{samples: "100000"},
+		{
+			samples: "0",
+			want:    "Metric 'latency' needs a positive number of samples",
+		},
+		{
+			samples: "100001",
+			want:    "Metric 'latency' can have at most 100000 samples",
+		},
+	} {
+		t.Run(tc.samples, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(`
+metrics:
+- name: "latency"
+  type: "histogram"
+valueSets:
+- valueSet:
+  - name: "latency"
+    dist: "normal(5, 1)"
+    samples: ` + tc.samples + "\n"))
+			if tc.want == "" && err != nil {
+				t.Errorf("Got %v, want no error", err)
+			}
+			if tc.want != "" && (err == nil || err.Error() != tc.want) {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
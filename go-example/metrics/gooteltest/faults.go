@@ -0,0 +1,234 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultLongLength  = 1024
+	defaultBurstSeries = 1000
+)
+
+// Faults describes pathological data mixed into the metrics sent. Each
+// probability is the chance, from 0 to 1, that the fault is injected into
+// one recorded value. A zero probability disables the fault.
+type Faults struct {
+
+	// The seed of the random choices, so that every run injects the same
+	// faults.
+	Seed int64 `yaml:"seed,omitempty"`
+
+	// Probability that a value or observation is replaced with NaN.
+	NaN float64 `yaml:"nan,omitempty"`
+
+	// Probability that a value or observation is replaced with +Inf or
+	// -Inf.
+	Infinity float64 `yaml:"infinity,omitempty"`
+
+	// Probability that a histogram observation is made negative.
+	NegativeObservation float64 `yaml:"negativeObservation,omitempty"`
+
+	// Probability that an attribute value is replaced with "".
+	EmptyAttributeValue float64 `yaml:"emptyAttributeValue,omitempty"`
+
+	// Probability that a metric name is padded to LongLength characters.
+	LongName float64 `yaml:"longName,omitempty"`
+
+	// Probability that an attribute value is padded to LongLength
+	// characters.
+	LongAttributeValue float64 `yaml:"longAttributeValue,omitempty"`
+
+	// The length of long names and attribute values. Default is 1024.
+	LongLength int `yaml:"longLength,omitempty"`
+
+	// Probability that an attribute value gets bytes that are not valid
+	// UTF-8.
+	InvalidUTF8 float64 `yaml:"invalidUTF8,omitempty"`
+
+	// Probability that a value is also recorded under BurstSeries new
+	// series.
+	CardinalityBurst float64 `yaml:"cardinalityBurst,omitempty"`
+
+	// The number of new series in a cardinality burst. Default is 1000.
+	BurstSeries int `yaml:"burstSeries,omitempty"`
+}
+
+func (f *Faults) fixDefaults() {
+	if f.LongLength == 0 {
+		f.LongLength = defaultLongLength
+	}
+	if f.BurstSeries == 0 {
+		f.BurstSeries = defaultBurstSeries
+	}
+}
+
+func checkFaults(config *Config) error {
+	f := config.Faults
+	if f == nil {
+		return nil
+	}
+	probabilities := []struct {
+		name  string
+		value float64
+	}{
+		{"nan", f.NaN},
+		{"infinity", f.Infinity},
+		{"negativeObservation", f.NegativeObservation},
+		{"emptyAttributeValue", f.EmptyAttributeValue},
+		{"longName", f.LongName},
+		{"longAttributeValue", f.LongAttributeValue},
+		{"invalidUTF8", f.InvalidUTF8},
+		{"cardinalityBurst", f.CardinalityBurst},
+	}
+	for _, p := range probabilities {
+		if p.value < 0 || p.value > 1 {
+			return fmt.Errorf(
+				"Fault probability %s must be between 0 and 1", p.name)
+		}
+	}
+	if f.LongLength < 0 {
+		return errors.New("Fault longLength must not be negative")
+	}
+	if f.BurstSeries < 0 {
+		return errors.New("Fault burstSeries must not be negative")
+	}
+	return nil
+}
+
+// FaultInjector injects the faults of a config into the values, names,
+// and attributes sent. A nil FaultInjector injects nothing. FaultInjector
+// instances are safe to use with multiple goroutines.
+type FaultInjector struct {
+	faults Faults
+
+	// lock protects the fields below.
+	lock   sync.Mutex
+	rand   *rand.Rand
+	bursts int
+}
+
+// NewFaultInjector returns a FaultInjector for faults. It returns nil if
+// faults is nil.
+func NewFaultInjector(faults *Faults) *FaultInjector {
+	if faults == nil {
+		return nil
+	}
+	return &FaultInjector{
+		faults: *faults,
+		rand:   rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+func (f *FaultInjector) happens(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.rand.Float64() < probability
+}
+
+// Value returns value, or NaN or an infinity in its place.
+func (f *FaultInjector) Value(value float64) float64 {
+	if f == nil {
+		return value
+	}
+	if f.happens(f.faults.NaN) {
+		return math.NaN()
+	}
+	if f.happens(f.faults.Infinity) {
+		if f.happens(0.5) {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+	return value
+}
+
+// Observations returns observations with faults injected into each one.
+// It leaves observations unchanged.
+func (f *FaultInjector) Observations(observations []float64) []float64 {
+	if f == nil {
+		return observations
+	}
+	result := make([]float64, len(observations))
+	for i, o := range observations {
+		o = f.Value(o)
+		if f.happens(f.faults.NegativeObservation) {
+			o = -math.Abs(o)
+		}
+		result[i] = o
+	}
+	return result
+}
+
+// Name returns name, possibly padded to a long name.
+func (f *FaultInjector) Name(name string) string {
+	if f == nil || !f.happens(f.faults.LongName) {
+		return name
+	}
+	return pad(name, f.faults.LongLength)
+}
+
+// Attributes returns attrs with faults injected into its values. If attrs
+// is empty and a fault happens, the result has a 'fault' attribute with
+// the faulty value. It leaves attrs unchanged.
+func (f *FaultInjector) Attributes(attrs map[string]string) map[string]string {
+	if f == nil {
+		return attrs
+	}
+	result := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		result[k] = f.attributeValue(v)
+	}
+	if len(attrs) == 0 {
+		if v := f.attributeValue("value"); v != "value" {
+			result["fault"] = v
+		}
+	}
+	return result
+}
+
+func (f *FaultInjector) attributeValue(value string) string {
+	if f.happens(f.faults.EmptyAttributeValue) {
+		return ""
+	}
+	if f.happens(f.faults.LongAttributeValue) {
+		value = pad(value, f.faults.LongLength)
+	}
+	if f.happens(f.faults.InvalidUTF8) {
+		value += "\xff\xfe"
+	}
+	return value
+}
+
+// Burst returns the extra attributes of each new series of a cardinality
+// burst, or nil if there is no burst. Each burst has its own series.
+func (f *FaultInjector) Burst() []map[string]string {
+	if f == nil || !f.happens(f.faults.CardinalityBurst) {
+		return nil
+	}
+	f.lock.Lock()
+	f.bursts++
+	burst := f.bursts
+	f.lock.Unlock()
+	result := make([]map[string]string, f.faults.BurstSeries)
+	for i := range result {
+		result[i] = map[string]string{
+			"burst.series": fmt.Sprintf("%d-%d", burst, i),
+		}
+	}
+	return result
+}
+
+func pad(s string, length int) string {
+	if len(s) >= length {
+		return s
+	}
+	return s + strings.Repeat("x", length-len(s))
+}
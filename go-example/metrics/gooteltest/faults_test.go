@@ -0,0 +1,217 @@
+package gooteltest
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReadConfigFaults(t *testing.T) {
+	for _, tc := range []struct {
+		faults string
+		want   string
+	}{
+		{faults: "{nan: 0, infinity: 1, longLength: 0}"},
+		{faults: "{seed: -3, cardinalityBurst: 0.5, burstSeries: 10}"},
+		{
+			faults: "{nan: -0.1}",
+			want:   "Fault probability nan must be between 0 and 1",
+		},
+		{
+			faults: "{invalidUTF8: 1.5}",
+			want:   "Fault probability invalidUTF8 must be between 0 and 1",
+		},
+		{
+			faults: "{longLength: -1}",
+			want:   "Fault longLength must not be negative",
+		},
+		{
+			faults: "{burstSeries: -1}",
+			want:   "Fault burstSeries must not be negative",
+		},
+	} {
+		t.Run(tc.faults, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(`
+metrics:
+- name: "temp"
+  type: "gauge"
+valueSets:
+- valueSet:
+  - name: "temp"
+    value: 1
+faults: ` + tc.faults + "\n"))
+			if tc.want == "" && err != nil {
+				t.Errorf("Got %v, want no error", err)
+			}
+			if tc.want != "" && (err == nil || err.Error() != tc.want) {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadConfigFaultDefaults(t *testing.T) {
+	config, err := ReadConfig(strings.NewReader(`
+metrics:
+- name: "temp"
+  type: "gauge"
+valueSets:
+- valueSet:
+  - name: "temp"
+    value: 1
+faults: {longName: 0.1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Faults.LongLength; got != defaultLongLength {
+		t.Errorf("Got longLength %d, want %d", got, defaultLongLength)
+	}
+	if got := config.Faults.BurstSeries; got != defaultBurstSeries {
+		t.Errorf("Got burstSeries %d, want %d", got, defaultBurstSeries)
+	}
+}
+
+func TestFaultProbability(t *testing.T) {
+	const calls = 10000
+	for _, probability := range []float64{0, 0.1, 0.5, 1} {
+		f := NewFaultInjector(&Faults{Seed: 7, NaN: probability})
+		nans := 0
+		for i := 0; i < calls; i++ {
+			if math.IsNaN(f.Value(1)) {
+				nans++
+			}
+		}
+		// With 10,000 calls, the share is within 2% of the probability
+		// for the fixed seed.
+		if got := float64(nans) / calls; math.Abs(got-probability) > 0.02 {
+			t.Errorf("Probability %v: got NaN %v of the time",
+				probability, got)
+		}
+	}
+}
+
+func TestFaultSeed(t *testing.T) {
+	faults := &Faults{Seed: 42, NaN: 0.5}
+	values := func() []bool {
+		f := NewFaultInjector(faults)
+		result := make([]bool, 100)
+		for i := range result {
+			result[i] = math.IsNaN(f.Value(1))
+		}
+		return result
+	}
+	if first, second := values(), values(); !reflect.DeepEqual(first, second) {
+		t.Error("The same seed injected different faults")
+	}
+}
+
+func TestFaultKinds(t *testing.T) {
+	attrs := map[string]string{"host": "a"}
+	for _, tc := range []struct {
+		name   string
+		faults Faults
+		check  func(f *FaultInjector) bool
+	}{
+		{
+			name:   "nan",
+			faults: Faults{NaN: 1},
+			check: func(f *FaultInjector) bool {
+				return math.IsNaN(f.Value(1))
+			},
+		},
+		{
+			name:   "infinity",
+			faults: Faults{Infinity: 1},
+			check: func(f *FaultInjector) bool {
+				return math.IsInf(f.Value(1), 0)
+			},
+		},
+		{
+			name:   "negativeObservation",
+			faults: Faults{NegativeObservation: 1},
+			check: func(f *FaultInjector) bool {
+				return reflect.DeepEqual(
+					f.Observations([]float64{1, 2}), []float64{-1, -2})
+			},
+		},
+		{
+			name:   "emptyAttributeValue",
+			faults: Faults{EmptyAttributeValue: 1},
+			check: func(f *FaultInjector) bool {
+				return f.Attributes(attrs)["host"] == ""
+			},
+		},
+		{
+			name:   "longName",
+			faults: Faults{LongName: 1, LongLength: 300},
+			check: func(f *FaultInjector) bool {
+				name := f.Name("temp")
+				return len(name) == 300 && strings.HasPrefix(name, "temp")
+			},
+		},
+		{
+			name:   "longAttributeValue",
+			faults: Faults{LongAttributeValue: 1, LongLength: 300},
+			check: func(f *FaultInjector) bool {
+				return len(f.Attributes(attrs)["host"]) == 300
+			},
+		},
+		{
+			name:   "invalidUTF8",
+			faults: Faults{InvalidUTF8: 1},
+			check: func(f *FaultInjector) bool {
+				return !utf8.ValidString(f.Attributes(attrs)["host"])
+			},
+		},
+		{
+			name:   "noAttributes",
+			faults: Faults{InvalidUTF8: 1},
+			check: func(f *FaultInjector) bool {
+				return !utf8.ValidString(f.Attributes(nil)["fault"])
+			},
+		},
+		{
+			name:   "cardinalityBurst",
+			faults: Faults{CardinalityBurst: 1, BurstSeries: 3},
+			check: func(f *FaultInjector) bool {
+				first, second := f.Burst(), f.Burst()
+				return len(first) == 3 &&
+					reflect.DeepEqual(first[2],
+						map[string]string{"burst.series": "1-2"}) &&
+					len(second) == 3 &&
+					second[0]["burst.series"] == "2-0"
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if !tc.check(NewFaultInjector(&tc.faults)) {
+				t.Error("Fault not injected")
+			}
+			// With no probability, nothing changes.
+			if tc.check(NewFaultInjector(&Faults{LongLength: 300})) {
+				t.Error("Fault injected with probability 0")
+			}
+		})
+	}
+	if attrs["host"] != "a" {
+		t.Error("Attributes changed the attributes passed in")
+	}
+}
+
+func TestNilFaultInjector(t *testing.T) {
+	var f *FaultInjector
+	attrs := map[string]string{"host": "a"}
+	if f.Value(1) != 1 ||
+		f.Name("temp") != "temp" ||
+		f.Burst() != nil ||
+		!reflect.DeepEqual(f.Observations([]float64{1}), []float64{1}) ||
+		!reflect.DeepEqual(f.Attributes(attrs), attrs) {
+		t.Error("A nil FaultInjector injected faults")
+	}
+	if NewFaultInjector(nil) != nil {
+		t.Error("Got a FaultInjector for no faults")
+	}
+}
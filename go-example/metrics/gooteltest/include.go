@@ -0,0 +1,281 @@
+package gooteltest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ReadConfigFromFiles reads and merges the yaml config files at paths in
+// order. Files ending in .properties are read with ReadJavaProfile. A
+// path that is a directory stands for the .yaml, .yml, and .properties
+// files in it in lexical order. Since each Java profile is a separate
+// run of the Java example, a directory may hold only one. The include section of each file lists
+// more files or directories, relative to that file, which are merged
+// before it. Each file is merged once even if it is included several
+// times.
+//
+// Metrics and services are merged by name. The same metric or service may
+// be defined in several files as long as the definitions are identical.
+// ValueSets, Phases, Traces, Logs, Anomalies, and Views are concatenated in
+// order. The other fields may be set in several files as long as they
+// have the same value. Conflicts are reported as errors.
+//
+// Placeholders in the files are replaced from environment variables. See
+// Params.
+func ReadConfigFromFiles(paths ...string) (*Config, error) {
+	return ReadConfigFromFilesWithParams(nil, paths...)
+}
+
+// ReadConfigFromFilesWithParams is like ReadConfigFromFiles but replaces
+// placeholders from params as well as from environment variables.
+func ReadConfigFromFilesWithParams(
+	params Params, paths ...string) (*Config, error) {
+	l := newConfigLoader(params)
+	for _, path := range paths {
+		if err := l.loadPath(path); err != nil {
+			return nil, err
+		}
+	}
+	return l.finish()
+}
+
+// configLoader merges config files into a single Config.
+type configLoader struct {
+	result Config
+	params Params
+
+	// The file each merged field was first set in, by field name.
+	setIn map[string]string
+
+	// By metric and service name, the index in result.
+	metrics  map[string]int
+	services map[string]int
+
+	// Files by absolute path being loaded and already merged.
+	loading map[string]bool
+	loaded  map[string]bool
+}
+
+func newConfigLoader(params Params) *configLoader {
+	return &configLoader{
+		params:   params,
+		setIn:    make(map[string]string),
+		metrics:  make(map[string]int),
+		services: make(map[string]int),
+		loading:  make(map[string]bool),
+		loaded:   make(map[string]bool),
+	}
+}
+
+func (l *configLoader) finish() (*Config, error) {
+	result := &l.result
+	result.fixDefaults()
+	if err := checkConfig(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadPath merges the config file at path or, if path is a directory, the
+// config files in it.
+func (l *configLoader) loadPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return l.loadFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	var files, profiles []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() &&
+			(ext == ".yaml" || ext == ".yml" || ext == ".properties") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+		if !entry.IsDir() && ext == ".properties" {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s has no config files", path)
+	}
+	// Each Java profile is a run of the Java example of its own. Merged,
+	// every service would send every histogram.
+	if len(profiles) > 1 {
+		sort.Strings(profiles)
+		return fmt.Errorf(
+			"%s has several Java profiles, which must be played one at a time: %s",
+			path,
+			strings.Join(profiles, ", "))
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if err := l.loadFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *configLoader) loadFile(fileName string) error {
+	abs, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	if l.loading[abs] {
+		return fmt.Errorf("%s includes itself", fileName)
+	}
+	if l.loaded[abs] {
+		return nil
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+	if err := l.load(f, fileName, filepath.Dir(fileName)); err != nil {
+		return err
+	}
+	l.loaded[abs] = true
+	return nil
+}
+
+// load merges the config read from r, resolving relative paths in it
+// against dir. name identifies the config in errors.
+func (l *configLoader) load(r io.Reader, name, dir string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	properties := strings.EqualFold(filepath.Ext(name), ".properties")
+	if properties {
+		data, err = l.params.ExpandProperties(data)
+	} else {
+		data, err = l.params.Expand(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	var c Config
+	if properties {
+		profile, err := ReadJavaProfile(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		c = *profile
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.SetStrict(true)
+		if err := decoder.Decode(&c); err != nil && err != io.EOF {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, include := range c.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+		if err := l.loadPath(include); err != nil {
+			return err
+		}
+	}
+	if c.ValueSource != "" && !filepath.IsAbs(c.ValueSource) {
+		c.ValueSource = filepath.Join(dir, c.ValueSource)
+	}
+	l.merge(&c, name)
+	return nil
+}
+
+// merge merges c, read from file, into the result.
+func (l *configLoader) merge(c *Config, file string) {
+	r := &l.result
+	if c.CollectPeriod != 0 {
+		l.set("collectPeriod", file, &r.CollectPeriod, c.CollectPeriod)
+	}
+	if c.AggregationTemporalitySelector != "" {
+		l.set(
+			"aggregationTemporalitySelector",
+			file,
+			&r.AggregationTemporalitySelector,
+			c.AggregationTemporalitySelector)
+	}
+	if c.NamePrefix != nil {
+		l.set("namePrefix", file, &r.NamePrefix, c.NamePrefix)
+	}
+	if c.Meter != "" {
+		l.set("meter", file, &r.Meter, c.Meter)
+	}
+	if c.GenerateExemplars {
+		r.GenerateExemplars = true
+	}
+	if c.ValueSource != "" {
+		l.set("valueSource", file, &r.ValueSource, c.ValueSource)
+	}
+	if c.Faults != nil {
+		l.set("faults", file, &r.Faults, c.Faults)
+	}
+	for _, service := range c.Services {
+		where := fmt.Sprintf("service '%s'", service.Name)
+		if i, ok := l.services[service.Name]; ok {
+			l.checkSame(where, file, r.Services[i], service)
+			continue
+		}
+		l.services[service.Name] = len(r.Services)
+		l.setIn[where] = file
+		r.Services = append(r.Services, service)
+	}
+	for _, metric := range c.Metrics {
+		where := fmt.Sprintf("metric '%s'", metric.Name)
+		if i, ok := l.metrics[metric.Name]; ok {
+			l.checkSame(where, file, r.Metrics[i], metric)
+			continue
+		}
+		l.metrics[metric.Name] = len(r.Metrics)
+		l.setIn[where] = file
+		r.Metrics = append(r.Metrics, metric)
+	}
+	r.ValueSets = append(r.ValueSets, c.ValueSets...)
+	r.Phases = append(r.Phases, c.Phases...)
+	r.Traces = append(r.Traces, c.Traces...)
+	r.Logs = append(r.Logs, c.Logs...)
+	r.Anomalies = append(r.Anomalies, c.Anomalies...)
+	r.Views = append(r.Views, c.Views...)
+}
+
+// set sets *field to value unless an earlier file set it to something
+// else. field must be a pointer to a field of the result.
+func (l *configLoader) set(
+	where, file string, field interface{}, value interface{}) {
+	if _, ok := l.setIn[where]; ok {
+		l.checkSame(where, file, reflect.ValueOf(field).Elem().Interface(), value)
+		return
+	}
+	l.setIn[where] = file
+	reflect.ValueOf(field).Elem().Set(reflect.ValueOf(value))
+}
+
+// checkSame records a conflict if the definition of where in file differs
+// from the one merged earlier.
+func (l *configLoader) checkSame(
+	where, file string, merged, definition interface{}) {
+	if reflect.DeepEqual(merged, definition) {
+		return
+	}
+	l.result.conflicts = append(l.result.conflicts, fmt.Errorf(
+		"Conflicting definitions of %s in %s and %s",
+		where, l.setIn[where], file))
+}
@@ -0,0 +1,59 @@
+package gooteltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadConfigFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": `
+collectPeriod: 1s
+metrics:
+- name: "requests"
+  type: "sum"
+`,
+		"b.properties": `
+service.name=histogram.service
+metric.name=latency
+metric.bucket.boundaries=0,20,40
+`,
+		"notes.txt": "not a config",
+	}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := ReadConfigFromFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range config.Metrics {
+		names = append(names, m.Name)
+	}
+	if len(names) != 2 || names[0] != "requests" || names[1] != "latency" {
+		t.Errorf("Got metrics %v, want [requests latency]", names)
+	}
+}
+
+func TestReadConfigFromDirectoryOfProfiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.properties", "a.properties"} {
+		err := os.WriteFile(
+			filepath.Join(dir, name), []byte("metric.name=latency\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := ReadConfigFromFiles(dir)
+	want := dir + " has several Java profiles, which must be played one at" +
+		" a time: a.properties, b.properties"
+	if err == nil || err.Error() != want {
+		t.Errorf("Got error %v, want %s", err, want)
+	}
+}
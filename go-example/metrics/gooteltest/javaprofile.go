@@ -0,0 +1,248 @@
+package gooteltest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JavaProfileInterval is how often the Java metric example exports.
+const JavaProfileInterval = time.Second
+
+// JavaProfileIterations is how many values the Java metric example
+// records. It records i*10 for i from 0 up to but excluding this.
+const JavaProfileIterations = 10
+
+// ReadJavaProfile reads a profile of the Java metric example, such as
+// java-metric-example/src/main/resources/env/config.cum.histo.properties,
+// and returns the config that sends the same histogram: same service,
+// instrumentation scope, name, unit, description, temporality, and
+// boundaries, and the same values at the same pace. An exponential
+// profile gets a view that makes its histogram exponential.
+// otel.exporter.otlp.endpoint is ignored; the tester reads its endpoint
+// from the environment as always. The service becomes a ServiceInfo, so
+// unlike in the Java example, its resource also has a service.instance.id
+// attribute.
+//
+// The returned config has not had its defaults filled in or been checked.
+// ReadConfigFromFiles does both for files ending in .properties.
+func ReadJavaProfile(r io.Reader) (*Config, error) {
+	props, err := ReadProperties(r)
+	if err != nil {
+		return nil, err
+	}
+	return JavaProfileConfig(props)
+}
+
+// JavaProfileConfig is like ReadJavaProfile for already parsed properties.
+func JavaProfileConfig(props map[string]string) (*Config, error) {
+	name := props["metric.name"]
+	if name == "" {
+		return nil, errors.New("profile has no metric.name")
+	}
+	metric := MetricInfo{
+		Name:        name,
+		Type:        MetricTypeHistogram,
+		Unit:        props["metric.unit"],
+		Description: props["description"],
+	}
+	switch props["aggregation.temporality"] {
+	case "delta":
+		metric.Temporality = DeltaAggregationSelector
+	case "cum", "cumulative", "":
+		// Like the Java example, anything but delta is cumulative.
+		metric.Temporality = CumulativeAggregationSelector
+	default:
+		return nil, fmt.Errorf(
+			"Unknown aggregation.temporality: %s",
+			props["aggregation.temporality"])
+	}
+	if b := props["metric.bucket.boundaries"]; b != "" {
+		for _, s := range strings.Split(b, ",") {
+			boundary, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid bucket boundary: %s", s)
+			}
+			metric.Boundaries = append(metric.Boundaries, boundary)
+		}
+	}
+	noPrefix := ""
+	result := &Config{
+		CollectPeriod: JavaProfileInterval,
+		NamePrefix:    &noPrefix,
+		Meter:         props["instrumentation.library.name"],
+		Metrics:       []MetricInfo{metric},
+	}
+	if props["metric.sub.type"] == "exponential" {
+		view, err := javaExponentialView(name, props)
+		if err != nil {
+			return nil, err
+		}
+		result.Views = []View{view}
+	}
+	if service := props["service.name"]; service != "" {
+		info := ServiceInfo{Name: service}
+		if app := props["application"]; app != "" {
+			info.Attributes = map[string]string{"application": app}
+		}
+		result.Services = []ServiceInfo{info}
+	}
+	for i := 0; i < JavaProfileIterations; i++ {
+		result.ValueSets = append(result.ValueSets, MetricValueSet{
+			ValueSet: []MetricValue{{
+				Name:         name,
+				Observations: Observations{float64(i * 10)},
+			}},
+		})
+	}
+	return result, nil
+}
+
+// javaExponentialView returns the view that makes the histogram with the
+// given name exponential like the Java example does: its scale property
+// is the maximum scale and its max.buckets property the maximum size.
+func javaExponentialView(name string, props map[string]string) (View, error) {
+	aggregation := &ViewAggregation{Type: AggregationExponentialHistogram}
+	if s := props["scale"]; s != "" {
+		scale, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+		if err != nil {
+			return View{}, fmt.Errorf("Invalid scale: %s", s)
+		}
+		maxScale := int32(scale)
+		aggregation.MaxScale = &maxScale
+	}
+	if s := props["max.buckets"]; s != "" {
+		size, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+		if err != nil {
+			return View{}, fmt.Errorf("Invalid max.buckets: %s", s)
+		}
+		aggregation.MaxSize = int32(size)
+	}
+	return View{Instrument: name, Aggregation: aggregation}, nil
+}
+
+// ReadProperties reads a Java properties file. It supports comments
+// starting with '#' or '!', keys separated from values by '=', ':', or
+// white space, lines continued with a trailing '\', and the escapes
+// that java.util.Properties supports.
+func ReadProperties(r io.Reader) (map[string]string, error) {
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	var logical strings.Builder
+	continued := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if continued {
+			line = strings.TrimLeft(line, " \t\f")
+		} else {
+			trimmed := strings.TrimLeft(line, " \t\f")
+			if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+				continue
+			}
+			line = trimmed
+		}
+		continued = endsWithContinuation(line)
+		if continued {
+			line = line[:len(line)-1]
+		}
+		logical.WriteString(line)
+		if continued {
+			continue
+		}
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+		logical.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logical.Len() > 0 {
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// endsWithContinuation reports whether line ends in an odd number of
+// backslashes.
+func endsWithContinuation(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func splitProperty(line string) (key, value string, err error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	if key, err = unescapeProperty(line[:end]); err != nil {
+		return "", "", err
+	}
+	if value, err = unescapeProperty(rest); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.ContainsRune(s, '\\') {
+		return s, nil
+	}
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			result.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			result.WriteByte('\t')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 'f':
+			result.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("Invalid \\u escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("Invalid \\u escape in %q", s)
+			}
+			result.WriteRune(rune(r))
+			i += 4
+		default:
+			result.WriteByte(s[i])
+		}
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,78 @@
+package gooteltest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// javaProfiles is where the Java metric example keeps its profiles.
+var javaProfiles = filepath.Join(
+	"..", "..", "..", "java-metric-example", "src", "main", "resources", "env")
+
+// javaValueSets are the values the Java example records: 0, 10, ..., 90.
+func javaValueSets(name string) []MetricValueSet {
+	var result []MetricValueSet
+	for i := 0; i < 10; i++ {
+		result = append(result, MetricValueSet{ValueSet: []MetricValue{{
+			Name:         name,
+			Observations: Observations{float64(i * 10)},
+		}}})
+	}
+	return result
+}
+
+func TestReadJavaProfile(t *testing.T) {
+	config, err := ReadConfigFromFiles(
+		filepath.Join(javaProfiles, "config.cum.histo.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noPrefix := ""
+	want := &Config{
+		CollectPeriod:                  JavaProfileInterval,
+		AggregationTemporalitySelector: CumulativeAggregationSelector,
+		NamePrefix:                     &noPrefix,
+		Meter:                          "sample.instrumentation.library",
+		Services: []ServiceInfo{{
+			Name:       "cum.histogram.service",
+			Replicas:   1,
+			Attributes: map[string]string{"application": "cum.histogram.app"},
+		}},
+		Metrics: []MetricInfo{{
+			Name:        "cum.histogram",
+			Type:        MetricTypeHistogram,
+			Temporality: CumulativeAggregationSelector,
+			Unit:        "ms",
+			Description: "Cumulative histogram",
+			Boundaries:  []float64{0, 20, 40, 60, 80, 100},
+		}},
+		ValueSets: javaValueSets("cum.histogram"),
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("Got %+v, want %+v", config, want)
+	}
+}
+
+func TestReadJavaExponentialProfile(t *testing.T) {
+	config, err := ReadConfigFromFiles(
+		filepath.Join(javaProfiles, "config.exp.delta.histo.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Metrics) != 1 || len(config.Views) != 1 {
+		t.Fatalf("Got metrics %+v and views %+v", config.Metrics, config.Views)
+	}
+	m, view := config.Metrics[0], config.Views[0]
+	if m.Temporality != DeltaAggregationSelector {
+		t.Errorf("Got temporality %s, want delta", m.Temporality)
+	}
+	if view.Instrument != m.Name ||
+		view.AggregationType() != AggregationExponentialHistogram {
+		t.Errorf("Got view %+v, want an exponentialHistogram of %s",
+			view, m.Name)
+	}
+	if !reflect.DeepEqual(config.ValueSets, javaValueSets(m.Name)) {
+		t.Errorf("Got value sets %+v", config.ValueSets)
+	}
+}
@@ -0,0 +1,267 @@
+package gooteltest
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// Wavefront ingestion limits.
+const (
+	WavefrontMaxNameLength = 256
+	WavefrontMaxTagLength  = 254
+	WavefrontMaxTags       = 20
+)
+
+// LintIssue is one problem that Wavefront would have with a metric.
+type LintIssue struct {
+
+	// What the problem is with, such as "metric 'foo'".
+	Where string
+
+	// What Wavefront does about it.
+	Problem string
+
+	// The original name or value.
+	Original string
+
+	// The sanitized name or value. Empty if it is dropped or rejected.
+	Sanitized string
+}
+
+func (i LintIssue) String() string {
+	if i.Sanitized == "" {
+		return fmt.Sprintf("%s: %s: %q", i.Where, i.Problem, i.Original)
+	}
+	return fmt.Sprintf(
+		"%s: %s: %q becomes %q", i.Where, i.Problem, i.Original, i.Sanitized)
+}
+
+// LintWavefront returns the names and attributes in config that Wavefront
+// would sanitize, truncate, or drop. Resource attributes count against the
+// limits on point tags along with the attributes of each metric. Metrics
+// are linted as views export them.
+func LintWavefront(config *Config) []LintIssue {
+	resources := []map[string]string{{
+		"service.name": "otel-otlp-go-service",
+		"application":  "otel-otlp-go-app",
+	}}
+	if len(config.Services) > 0 {
+		resources = nil
+		for _, service := range config.Services {
+			res := map[string]string{
+				"service.name":        service.Name,
+				"service.instance.id": service.Name + "-0",
+			}
+			for k, v := range service.Attributes {
+				res[k] = v
+			}
+			resources = append(resources, res)
+		}
+	}
+	var l linter
+	for _, res := range resources {
+		for _, m := range config.Metrics {
+			view := config.ViewFor(config.InstrumentName(m))
+			if view.AggregationType() == AggregationDrop {
+				continue
+			}
+			l.lintSeries(
+				config.ExportedName(m), res, view.FilterAttributes(m.Attributes))
+		}
+	}
+	return l.issues
+}
+
+// LintWavefrontOTLP is like LintWavefront for OTLP metrics.
+func LintWavefrontOTLP(resourceMetrics []*metricspb.ResourceMetrics) []LintIssue {
+	var l linter
+	for _, rm := range resourceMetrics {
+		res := ResourceAttributes(rm.GetResource())
+		for _, metric := range OTLPMetrics(rm) {
+			for _, attrs := range otlpPointAttributes(metric) {
+				l.lintSeries(metric.GetName(), res, attrs)
+			}
+		}
+	}
+	return l.issues
+}
+
+func otlpPointAttributes(metric *metricspb.Metric) []map[string]string {
+	var result []map[string]string
+	switch data := metric.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, p := range data.Gauge.GetDataPoints() {
+			result = append(result, OTLPAttributes(p.GetAttributes()))
+		}
+	case *metricspb.Metric_Sum:
+		for _, p := range data.Sum.GetDataPoints() {
+			result = append(result, OTLPAttributes(p.GetAttributes()))
+		}
+	case *metricspb.Metric_Histogram:
+		for _, p := range data.Histogram.GetDataPoints() {
+			result = append(result, OTLPAttributes(p.GetAttributes()))
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		for _, p := range data.ExponentialHistogram.GetDataPoints() {
+			result = append(result, OTLPAttributes(p.GetAttributes()))
+		}
+	case *metricspb.Metric_Summary:
+		for _, p := range data.Summary.GetDataPoints() {
+			result = append(result, OTLPAttributes(p.GetAttributes()))
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, nil)
+	}
+	return result
+}
+
+// linter collects LintIssues, reporting each one once.
+type linter struct {
+	issues []LintIssue
+	seen   map[string]bool
+}
+
+func (l *linter) add(issue LintIssue) {
+	if l.seen == nil {
+		l.seen = make(map[string]bool)
+	}
+	key := issue.String()
+	if !l.seen[key] {
+		l.seen[key] = true
+		l.issues = append(l.issues, issue)
+	}
+}
+
+func (l *linter) lintSeries(name string, res, attrs map[string]string) {
+	where := fmt.Sprintf("metric '%s'", name)
+	if len(name) > WavefrontMaxNameLength {
+		l.add(LintIssue{
+			Where: where,
+			Problem: fmt.Sprintf(
+				"name longer than %d characters is rejected",
+				WavefrontMaxNameLength),
+			Original: name,
+		})
+	} else if sanitized := SanitizeWavefrontName(name); sanitized != name {
+		l.add(LintIssue{
+			Where:     where,
+			Problem:   "invalid characters in name are replaced",
+			Original:  name,
+			Sanitized: sanitized,
+		})
+	}
+	// Resource attributes are reported once per resource rather than once
+	// per metric.
+	tags := 0
+	resWhere := fmt.Sprintf("resource of service '%s'", res["service.name"])
+	l.lintTags(resWhere, res, &tags)
+	l.lintTags(where, attrs, &tags)
+	if tags > WavefrontMaxTags {
+		l.add(LintIssue{
+			Where: where,
+			Problem: fmt.Sprintf(
+				"%d point tags is more than the limit of %d, so points are rejected",
+				tags,
+				WavefrontMaxTags),
+			Original: name,
+		})
+	}
+}
+
+// lintTags lints attrs, which become point tags, and adds the number of
+// tags that are kept to tags.
+func (l *linter) lintTags(
+	where string, attrs map[string]string, tags *int) {
+	for _, k := range sortedKeys(attrs) {
+		v := attrs[k]
+		tagWhere := fmt.Sprintf("%s attribute '%s'", where, k)
+		if v == "" {
+			l.add(LintIssue{
+				Where:    tagWhere,
+				Problem:  "empty value is dropped",
+				Original: v,
+			})
+			continue
+		}
+		*tags++
+		key := SanitizeWavefrontName(k)
+		if key != k {
+			l.add(LintIssue{
+				Where:     tagWhere,
+				Problem:   "invalid characters in key are replaced",
+				Original:  k,
+				Sanitized: key,
+			})
+		}
+		value := v
+		if !utf8.ValidString(value) {
+			value = strings.ToValidUTF8(value, "�")
+			l.add(LintIssue{
+				Where:     tagWhere,
+				Problem:   "invalid UTF-8 in value is replaced",
+				Original:  v,
+				Sanitized: value,
+			})
+		}
+		if len(key)+len(value) > WavefrontMaxTagLength {
+			truncated := truncateUTF8(value, WavefrontMaxTagLength-len(key))
+			if truncated == "" {
+				l.add(LintIssue{
+					Where: tagWhere,
+					Problem: fmt.Sprintf(
+						"key longer than %d characters is dropped",
+						WavefrontMaxTagLength),
+					Original: k,
+				})
+				*tags--
+				continue
+			}
+			l.add(LintIssue{
+				Where: tagWhere,
+				Problem: fmt.Sprintf(
+					"key and value longer than %d characters are truncated",
+					WavefrontMaxTagLength),
+				Original:  v,
+				Sanitized: truncated,
+			})
+		}
+	}
+}
+
+// SanitizeWavefrontName returns name with each character that Wavefront
+// does not allow in metric names and point tag keys replaced with '-'.
+// Wavefront allows letters, digits, '-', '_', and '.', as well as a
+// leading '~' or delta character.
+func SanitizeWavefrontName(name string) string {
+	var result strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.':
+		case i == 0 && (r == '~' || r == '∆' || r == 'Δ'):
+		default:
+			r = '-'
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// truncateUTF8 returns the longest prefix of s that has at most n bytes
+// and does not split a character.
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,156 @@
+package gooteltest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lintConfig returns a config with one gauge 'temp' with attribute host
+// sent from service 'svc'. Its resource has two attributes.
+func lintConfig(t *testing.T) *Config {
+	t.Helper()
+	config, err := ReadConfig(strings.NewReader(`
+services:
+- name: "svc"
+metrics:
+- name: "temp"
+  type: "gauge"
+  attributes:
+    host: "a"
+valueSets:
+- valueSet:
+  - name: "temp"
+    value: 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// setAttribute replaces the attributes of the metric of config.
+func setAttribute(config *Config, key, value string) {
+	config.Metrics[0].Attributes = map[string]string{key: value}
+}
+
+// manyAttributes gives the metric of config n attributes.
+func manyAttributes(config *Config, n int) {
+	config.Metrics[0].Attributes = make(map[string]string)
+	for i := 0; i < n; i++ {
+		config.Metrics[0].Attributes[fmt.Sprintf("k%d", i)] = "v"
+	}
+}
+
+func TestLintWavefront(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("x", n) }
+	for _, tc := range []struct {
+		name string
+
+		// triggers breaks the rule; passes comes as close as the rule
+		// allows.
+		triggers func(c *Config)
+		passes   func(c *Config)
+
+		where   string
+		problem string
+	}{
+		{
+			name:     "longName",
+			triggers: func(c *Config) { c.Metrics[0].Name = long(257) },
+			passes:   func(c *Config) { c.Metrics[0].Name = long(256) },
+			where:    "metric '" + long(257) + "'",
+			problem:  "name longer than 256 characters is rejected",
+		},
+		{
+			name:     "invalidName",
+			triggers: func(c *Config) { c.Metrics[0].Name = "temp/c" },
+			passes:   func(c *Config) { c.Metrics[0].Name = "~temp_c.x-y" },
+			where:    "metric 'temp/c'",
+			problem:  "invalid characters in name are replaced",
+		},
+		{
+			name:     "emptyValue",
+			triggers: func(c *Config) { setAttribute(c, "host", "") },
+			passes:   func(c *Config) { setAttribute(c, "host", " ") },
+			where:    "metric 'temp' attribute 'host'",
+			problem:  "empty value is dropped",
+		},
+		{
+			name:     "invalidKey",
+			triggers: func(c *Config) { setAttribute(c, "host name", "a") },
+			passes:   func(c *Config) { setAttribute(c, "host_name", "a") },
+			where:    "metric 'temp' attribute 'host name'",
+			problem:  "invalid characters in key are replaced",
+		},
+		{
+			name:     "invalidUTF8",
+			triggers: func(c *Config) { setAttribute(c, "host", "a\xff") },
+			passes:   func(c *Config) { setAttribute(c, "host", "ä") },
+			where:    "metric 'temp' attribute 'host'",
+			problem:  "invalid UTF-8 in value is replaced",
+		},
+		{
+			name:     "longKey",
+			triggers: func(c *Config) { setAttribute(c, long(254), "a") },
+			passes:   func(c *Config) { setAttribute(c, long(253), "a") },
+			where:    "metric 'temp' attribute '" + long(254) + "'",
+			problem:  "key longer than 254 characters is dropped",
+		},
+		{
+			name:     "longValue",
+			triggers: func(c *Config) { setAttribute(c, "host", long(251)) },
+			passes:   func(c *Config) { setAttribute(c, "host", long(250)) },
+			where:    "metric 'temp' attribute 'host'",
+			problem:  "key and value longer than 254 characters are truncated",
+		},
+		{
+			// The resource has two attributes.
+			name:     "tooManyTags",
+			triggers: func(c *Config) { manyAttributes(c, 19) },
+			passes:   func(c *Config) { manyAttributes(c, 18) },
+			where:    "metric 'temp'",
+			problem:  "21 point tags is more than the limit of 20",
+		},
+		{
+			name: "resource",
+			triggers: func(c *Config) {
+				c.Services[0].Attributes = map[string]string{"a b": "c"}
+			},
+			passes: func(c *Config) {
+				c.Services[0].Attributes = map[string]string{"a_b": "c"}
+			},
+			where:   "resource of service 'svc' attribute 'a b'",
+			problem: "invalid characters in key are replaced",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := lintConfig(t)
+			tc.passes(config)
+			if issues := LintWavefront(config); len(issues) != 0 {
+				t.Errorf("Got %v, want no issues", issues)
+			}
+			config = lintConfig(t)
+			tc.triggers(config)
+			issues := LintWavefront(config)
+			if len(issues) != 1 ||
+				issues[0].Where != tc.where ||
+				!strings.HasPrefix(issues[0].Problem, tc.problem) {
+				t.Errorf("Got %v, want one issue with %s: %s",
+					issues, tc.where, tc.problem)
+			}
+		})
+	}
+}
+
+func TestLintWavefrontDroppedMetric(t *testing.T) {
+	config := lintConfig(t)
+	config.Metrics[0].Name = "temp/c"
+	config.Views = []View{{
+		Instrument:  "temp/c",
+		Aggregation: &ViewAggregation{Type: AggregationDrop},
+	}}
+	if issues := LintWavefront(config); len(issues) != 0 {
+		t.Errorf("Got %v for a dropped metric, want no issues", issues)
+	}
+}
@@ -0,0 +1,140 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+)
+
+var severityNames = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// The most and fewest records per second a LogStream may send. The
+// fewest is one an hour.
+const (
+	MaxLogRate = 10000
+	MinLogRate = 1.0 / 3600
+)
+
+// LogStream describes log records that get sent at a fixed rate.
+type LogStream struct {
+	Name string `yaml:"name"`
+
+	// The service.name of the service sending the records. Works the same
+	// way as Service in SpanInfo.
+	Service string `yaml:"service,omitempty"`
+
+	// How many records to send per second, from MinLogRate to MaxLogRate.
+	Rate float64 `yaml:"rate"`
+
+	// One of TRACE, DEBUG, INFO, WARN, ERROR, or FATAL optionally followed
+	// by 2, 3, or 4 as in 'WARN2'. Default is INFO.
+	Severity string `yaml:"severity,omitempty"`
+
+	// The body of each record as a text/template. The template can use
+	// {{.Stream}}, {{.Service}}, {{.Count}}, and {{.Time}}.
+	Body string `yaml:"body"`
+
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+
+	// If set, each record carries the trace and span IDs of the most
+	// recently sent trace of this trace scenario.
+	Trace string `yaml:"trace,omitempty"`
+
+	// The span in Trace whose IDs the records carry. Default is the
+	// root span.
+	Span string `yaml:"span,omitempty"`
+}
+
+// LogBodyData is what the Body template of a LogStream gets executed on.
+type LogBodyData struct {
+	Stream  string
+	Service string
+	Count   int
+	Time    string
+}
+
+// SeverityNumber returns the OTLP severity number of severity, a string
+// such as 'INFO' or 'ERROR3', and false if severity is not valid.
+func SeverityNumber(severity string) (int, bool) {
+	name := strings.ToUpper(severity)
+	offset := 0
+	if n := len(name); n > 0 && name[n-1] >= '2' && name[n-1] <= '4' {
+		offset = int(name[n-1] - '1')
+		name = name[:n-1]
+	}
+	for i, s := range severityNames {
+		if s == name {
+			return 4*i + 1 + offset, true
+		}
+	}
+	return 0, false
+}
+
+// BodyTemplate returns the parsed Body of this LogStream.
+func (l *LogStream) BodyTemplate() (*template.Template, error) {
+	return template.New(l.Name).Parse(l.Body)
+}
+
+func checkLogs(config *Config) error {
+	spansByScenario := make(map[string]map[string]struct{})
+	for _, scenario := range config.Traces {
+		names := make(map[string]struct{})
+		scenario.Root.spanNames(names)
+		spansByScenario[scenario.Name] = names
+	}
+	namesSeen := make(map[string]struct{})
+	for _, stream := range config.Logs {
+		if stream.Name == "" {
+			return errors.New("logs must have a name")
+		}
+		if _, ok := namesSeen[stream.Name]; ok {
+			return fmt.Errorf("Duplicate logs: %s", stream.Name)
+		}
+		namesSeen[stream.Name] = struct{}{}
+		if stream.Rate <= 0 || math.IsNaN(stream.Rate) {
+			return fmt.Errorf("Logs '%s' needs a positive rate", stream.Name)
+		}
+		if stream.Rate < MinLogRate {
+			return fmt.Errorf(
+				"Logs '%s' rate must be at least one per hour", stream.Name)
+		}
+		if stream.Rate > MaxLogRate {
+			return fmt.Errorf(
+				"Logs '%s' rate must be at most %d per second",
+				stream.Name,
+				MaxLogRate)
+		}
+		if _, ok := SeverityNumber(stream.Severity); !ok {
+			return fmt.Errorf(
+				"Logs '%s' has unknown severity: %s",
+				stream.Name,
+				stream.Severity)
+		}
+		if _, err := stream.BodyTemplate(); err != nil {
+			return fmt.Errorf("Logs '%s': %v", stream.Name, err)
+		}
+		if stream.Trace == "" {
+			if stream.Span != "" {
+				return fmt.Errorf(
+					"Logs '%s' has a span but no trace", stream.Name)
+			}
+			continue
+		}
+		names, ok := spansByScenario[stream.Trace]
+		if !ok {
+			return fmt.Errorf(
+				"Logs '%s' refers to unknown trace: %s",
+				stream.Name,
+				stream.Trace)
+		}
+		if _, ok := names[stream.Span]; stream.Span != "" && !ok {
+			return fmt.Errorf(
+				"Logs '%s' refers to unknown span: %s",
+				stream.Name,
+				stream.Span)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package gooteltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadConfigLogRate(t *testing.T) {
+	for _, tc := range []struct {
+		rate string
+		want string
+	}{
+		{rate: "0.5"},
+		{rate: "10000"},
+		{rate: "0.0003"},
+		{rate: "-1", want: "Logs 'errors' needs a positive rate"},
+		{
+			rate: "1e-10",
+			want: "Logs 'errors' rate must be at least one per hour",
+		},
+		{
+			rate: "0.0002",
+			want: "Logs 'errors' rate must be at least one per hour",
+		},
+		{rate: ".nan", want: "Logs 'errors' needs a positive rate"},
+		{
+			rate: "10001",
+			want: "Logs 'errors' rate must be at most 10000 per second",
+		},
+		{
+			rate: ".inf",
+			want: "Logs 'errors' rate must be at most 10000 per second",
+		},
+	} {
+		t.Run(tc.rate, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(`
+logs:
+- name: errors
+  rate: ` + tc.rate + `
+  body: "failed"
+`))
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.want {
+				t.Errorf("Got error %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
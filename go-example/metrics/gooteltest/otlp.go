@@ -0,0 +1,157 @@
+package gooteltest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// AddOTLP adds the gauge, sum, and histogram points in resourceMetrics.
+// Exponential histograms and summaries can't be replayed, so they are
+// skipped. AddOTLP adds every point it can; if it skips any, it returns
+// an error describing the first one.
+func (b *ConfigBuilder) AddOTLP(
+	resourceMetrics []*metricspb.ResourceMetrics) error {
+	var firstErr error
+	for _, rm := range resourceMetrics {
+		res := ResourceAttributes(rm.GetResource())
+		for _, metric := range OTLPMetrics(rm) {
+			if err := b.addOTLPMetric(res, metric); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// OTLPMetrics returns all the metrics in rm.
+func OTLPMetrics(rm *metricspb.ResourceMetrics) []*metricspb.Metric {
+	var result []*metricspb.Metric
+	for _, sm := range rm.GetScopeMetrics() {
+		result = append(result, sm.GetMetrics()...)
+	}
+	return result
+}
+
+func (b *ConfigBuilder) addOTLPMetric(
+	res map[string]string, metric *metricspb.Metric) error {
+	if metric.GetUnit() != "" || metric.GetDescription() != "" {
+		b.SetMetadata(metric.GetName(), metric.GetUnit(), metric.GetDescription())
+	}
+	id := func(attrs []*commonpb.KeyValue) SeriesID {
+		return SeriesID{
+			Resource:   res,
+			Metric:     metric.GetName(),
+			Attributes: OTLPAttributes(attrs),
+		}
+	}
+	switch data := metric.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, p := range data.Gauge.GetDataPoints() {
+			b.AddGauge(
+				id(p.GetAttributes()),
+				unixNano(p.GetTimeUnixNano()),
+				numberValue(p))
+		}
+	case *metricspb.Metric_Sum:
+		temporality := otlpTemporality(data.Sum.GetAggregationTemporality())
+		for _, p := range data.Sum.GetDataPoints() {
+			b.AddSum(
+				id(p.GetAttributes()),
+				unixNano(p.GetTimeUnixNano()),
+				numberValue(p),
+				temporality,
+				data.Sum.GetIsMonotonic())
+		}
+	case *metricspb.Metric_Histogram:
+		temporality := otlpTemporality(data.Histogram.GetAggregationTemporality())
+		var firstErr error
+		for _, p := range data.Histogram.GetDataPoints() {
+			err := b.AddHistogram(
+				id(p.GetAttributes()),
+				unixNano(p.GetTimeUnixNano()),
+				temporality,
+				p.GetExplicitBounds(),
+				p.GetBucketCounts())
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	case *metricspb.Metric_ExponentialHistogram:
+		return fmt.Errorf(
+			"skipped exponential histogram '%s'", metric.GetName())
+	case *metricspb.Metric_Summary:
+		return fmt.Errorf("skipped summary '%s'", metric.GetName())
+	}
+	return nil
+}
+
+// ResourceAttributes returns the attributes of res as strings.
+func ResourceAttributes(res *resourcepb.Resource) map[string]string {
+	return OTLPAttributes(res.GetAttributes())
+}
+
+// OTLPAttributes returns attrs as strings. It returns nil if attrs is
+// empty.
+func OTLPAttributes(attrs []*commonpb.KeyValue) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		result[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return result
+}
+
+func anyValueString(v *commonpb.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(x.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(x.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return string(x.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		var parts []string
+		for _, elem := range x.ArrayValue.GetValues() {
+			parts = append(parts, anyValueString(elem))
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		var parts []string
+		for _, kv := range x.KvlistValue.GetValues() {
+			parts = append(parts, kv.GetKey()+"="+anyValueString(kv.GetValue()))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+	return ""
+}
+
+func numberValue(p *metricspb.NumberDataPoint) float64 {
+	if x, ok := p.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(x.AsInt)
+	}
+	return p.GetAsDouble()
+}
+
+func otlpTemporality(t metricspb.AggregationTemporality) string {
+	if t == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		return DeltaAggregationSelector
+	}
+	return CumulativeAggregationSelector
+}
+
+func unixNano(t uint64) time.Time {
+	return time.Unix(0, int64(t))
+}
@@ -0,0 +1,264 @@
+package gooteltest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	SignalMetrics = "metrics"
+	SignalTraces  = "traces"
+	SignalLogs    = "logs"
+)
+
+// OTLPBatch is one batch of telemetry read from an OTLP file. Exactly one
+// of the fields is set.
+type OTLPBatch struct {
+	Metrics *colmetricspb.ExportMetricsServiceRequest
+	Traces  *coltracepb.ExportTraceServiceRequest
+	Logs    *collogspb.ExportLogsServiceRequest
+}
+
+func (b OTLPBatch) message() proto.Message {
+	switch {
+	case b.Metrics != nil:
+		return b.Metrics
+	case b.Traces != nil:
+		return b.Traces
+	default:
+		return b.Logs
+	}
+}
+
+// FirstTime returns the earliest time at which something in this batch
+// happened or the zero time if it has none. That is the time of data
+// points, span events, and log records and the start of spans. The start
+// times of cumulative data points can be long before the batch, so they
+// don't count.
+func (b OTLPBatch) FirstTime() time.Time {
+	var first uint64 = math.MaxUint64
+	walkTimes(b.message().ProtoReflect(), func(
+		fd protoreflect.FieldDescriptor, t uint64) uint64 {
+		if t != 0 && t < first && isEventTime(fd) {
+			first = t
+		}
+		return t
+	})
+	if first == math.MaxUint64 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(first))
+}
+
+// isEventTime reports whether fd is the time at which its message
+// happened.
+func isEventTime(fd protoreflect.FieldDescriptor) bool {
+	switch fd.Name() {
+	case "time_unix_nano", "observed_time_unix_nano":
+		return true
+	case "start_time_unix_nano":
+		return fd.ContainingMessage().Name() == "Span"
+	}
+	return false
+}
+
+// MapTimes replaces every timestamp t in this batch, including start
+// times, with f(t).
+func (b OTLPBatch) MapTimes(f func(t time.Time) time.Time) {
+	walkTimes(b.message().ProtoReflect(), func(
+		_ protoreflect.FieldDescriptor, t uint64) uint64 {
+		if t == 0 {
+			return 0
+		}
+		return uint64(f(time.Unix(0, int64(t))).UnixNano())
+	})
+}
+
+// walkTimes calls f on every *_time_unix_nano field in m and its
+// descendants and sets the field to what f returns.
+func walkTimes(
+	m protoreflect.Message,
+	f func(fd protoreflect.FieldDescriptor, t uint64) uint64) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Kind() == protoreflect.Fixed64Kind &&
+			strings.HasSuffix(string(fd.Name()), "time_unix_nano"):
+			m.Set(fd, protoreflect.ValueOfUint64(f(fd, v.Uint())))
+		case fd.Kind() == protoreflect.MessageKind && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				walkTimes(list.Get(i).Message(), f)
+			}
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsMap():
+			walkTimes(v.Message(), f)
+		}
+		return true
+	})
+}
+
+// OTLPReader reads OTLP batches one at a time from a file written by the
+// OTEL collector's file exporter. It handles both formats of that
+// exporter: JSON with one batch per line, and protobuf with each batch
+// preceded by its length as a 4 byte big endian integer.
+type OTLPReader struct {
+	r      *bufio.Reader
+	json   bool
+	signal string
+}
+
+// NewOTLPReader returns a new OTLPReader reading from r. Whether the data
+// is JSON or protobuf is detected from the first byte. JSON lines say
+// what signal they carry, but protobuf batches don't, so signal gives the
+// signal of protobuf batches: 'metrics', 'traces', or 'logs'.
+func NewOTLPReader(r io.Reader, signal string) (*OTLPReader, error) {
+	switch signal {
+	case SignalMetrics, SignalTraces, SignalLogs:
+	default:
+		return nil, fmt.Errorf("Unknown signal: %s", signal)
+	}
+	br := bufio.NewReaderSize(r, 1<<20)
+	first, err := br.Peek(1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &OTLPReader{
+		r:      br,
+		json:   len(first) == 1 && (first[0] == '{' || first[0] == '\n'),
+		signal: signal,
+	}, nil
+}
+
+// Next returns the next batch. It returns io.EOF when there are no more
+// batches.
+func (o *OTLPReader) Next() (OTLPBatch, error) {
+	if o.json {
+		return o.nextJSON()
+	}
+	return o.nextProto()
+}
+
+func (o *OTLPReader) nextProto() (OTLPBatch, error) {
+	var size uint32
+	if err := binary.Read(o.r, binary.BigEndian, &size); err != nil {
+		return OTLPBatch{}, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(o.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return OTLPBatch{}, err
+	}
+	batch := newOTLPBatch(o.signal)
+	if err := proto.Unmarshal(data, batch.message()); err != nil {
+		return OTLPBatch{}, err
+	}
+	return batch, nil
+}
+
+func (o *OTLPReader) nextJSON() (OTLPBatch, error) {
+	for {
+		line, err := o.r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return parseJSONBatch(line)
+		}
+		if err != nil {
+			return OTLPBatch{}, err
+		}
+	}
+}
+
+func newOTLPBatch(signal string) OTLPBatch {
+	switch signal {
+	case SignalTraces:
+		return OTLPBatch{Traces: &coltracepb.ExportTraceServiceRequest{}}
+	case SignalLogs:
+		return OTLPBatch{Logs: &collogspb.ExportLogsServiceRequest{}}
+	default:
+		return OTLPBatch{Metrics: &colmetricspb.ExportMetricsServiceRequest{}}
+	}
+}
+
+func parseJSONBatch(line []byte) (OTLPBatch, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return OTLPBatch{}, err
+	}
+	var batch OTLPBatch
+	switch {
+	case fields["resourceMetrics"] != nil:
+		batch = newOTLPBatch(SignalMetrics)
+	case fields["resourceSpans"] != nil:
+		batch = newOTLPBatch(SignalTraces)
+	case fields["resourceLogs"] != nil:
+		batch = newOTLPBatch(SignalLogs)
+	default:
+		return OTLPBatch{}, errors.New(
+			"JSON line has no resourceMetrics, resourceSpans, or resourceLogs")
+	}
+	// OTLP JSON encodes trace and span IDs as hex, but protojson expects
+	// base64 for bytes fields.
+	if err := hexIDsToBase64(fields); err != nil {
+		return OTLPBatch{}, err
+	}
+	converted, err := json.Marshal(fields)
+	if err != nil {
+		return OTLPBatch{}, err
+	}
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(
+		converted, batch.message())
+	if err != nil {
+		return OTLPBatch{}, err
+	}
+	return batch, nil
+}
+
+var idFields = map[string]bool{
+	"traceId":      true,
+	"spanId":       true,
+	"parentSpanId": true,
+}
+
+func hexIDsToBase64(v interface{}) error {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, field := range x {
+			if s, ok := field.(string); ok && idFields[k] {
+				id, err := hex.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("invalid %s: %s", k, s)
+				}
+				x[k] = base64.StdEncoding.EncodeToString(id)
+				continue
+			}
+			if err := hexIDsToBase64(field); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, elem := range x {
+			if err := hexIDsToBase64(elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
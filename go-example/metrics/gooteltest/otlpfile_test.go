@@ -0,0 +1,82 @@
+package gooteltest
+
+import (
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func sumBatch(start uint64, times ...uint64) OTLPBatch {
+	var points []*metricspb.NumberDataPoint
+	for _, t := range times {
+		points = append(points, &metricspb.NumberDataPoint{
+			StartTimeUnixNano: start,
+			TimeUnixNano:      t,
+		})
+	}
+	return OTLPBatch{Metrics: &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "requests",
+					Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+						DataPoints: points,
+					}},
+				}},
+			}},
+		}},
+	}}
+}
+
+func TestFirstTime(t *testing.T) {
+	span := OTLPBatch{Traces: &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{{
+					StartTimeUnixNano: 2000,
+					EndTimeUnixNano:   3000,
+					Events: []*tracepb.Span_Event{{
+						TimeUnixNano: 2500,
+					}},
+				}},
+			}},
+		}},
+	}}
+	for _, tc := range []struct {
+		name  string
+		batch OTLPBatch
+		want  uint64
+	}{
+		{name: "cumulative", batch: sumBatch(1000, 7000, 5000), want: 5000},
+		{name: "noStart", batch: sumBatch(0, 6000), want: 6000},
+		{name: "span", batch: span, want: 2000},
+		{name: "empty", batch: sumBatch(1000)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := time.Time{}
+			if tc.want != 0 {
+				want = time.Unix(0, int64(tc.want))
+			}
+			if got := tc.batch.FirstTime(); !got.Equal(want) {
+				t.Errorf("Got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMapTimesMovesStartTimes(t *testing.T) {
+	batch := sumBatch(1000, 5000)
+	batch.MapTimes(func(t time.Time) time.Time {
+		return t.Add(100)
+	})
+	point := batch.Metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].
+		GetSum().DataPoints[0]
+	if point.StartTimeUnixNano != 1100 || point.TimeUnixNano != 5100 {
+		t.Errorf("Got start %d and time %d, want 1100 and 5100",
+			point.StartTimeUnixNano, point.TimeUnixNano)
+	}
+}
@@ -0,0 +1,170 @@
+package gooteltest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Params are values for the placeholders in config files by name. They
+// take precedence over environment variables of the same name. Params
+// implement flag.Value so that they can be set with repeated flags.
+//
+// Before a config file is decoded, each ${NAME} in it is replaced with
+// the value of NAME, and each ${NAME:-default} with the value of NAME or,
+// if NAME is unset or empty, with default. A NAME that is unset and has
+// no default is an error. $${ stands for a literal ${. Comments are left
+// alone. Replacement is textual, so values that YAML would read
+// specially need quoting in the config file.
+type Params map[string]string
+
+// Lookup returns the value of the parameter or environment variable name.
+func (p Params) Lookup(name string) (string, bool) {
+	if value, ok := p[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// String returns the parameters as name=value separated by commas.
+func (p Params) String() string {
+	var result []string
+	for _, name := range sortedKeys(p) {
+		result = append(result, name+"="+p[name])
+	}
+	return strings.Join(result, ",")
+}
+
+// Set sets a parameter from a string of the form name=value.
+func (p Params) Set(nameValue string) error {
+	i := strings.IndexByte(nameValue, '=')
+	if i <= 0 {
+		return fmt.Errorf("Parameter must be name=value: %s", nameValue)
+	}
+	p[nameValue[:i]] = nameValue[i+1:]
+	return nil
+}
+
+// Expand returns the yaml in data with its placeholders replaced.
+// Placeholders in comments are left alone.
+func (p Params) Expand(data []byte) ([]byte, error) {
+	return p.expandLines(data, func(line []byte, _ bool) int {
+		return yamlCommentStart(line)
+	})
+}
+
+// ExpandProperties is like Expand for a Java properties file.
+func (p Params) ExpandProperties(data []byte) ([]byte, error) {
+	return p.expandLines(data, func(line []byte, continued bool) int {
+		trimmed := bytes.TrimLeft(line, " \t\f")
+		if !continued && len(trimmed) > 0 &&
+			(trimmed[0] == '#' || trimmed[0] == '!') {
+			return 0
+		}
+		return len(line)
+	})
+}
+
+// expandLines replaces the placeholders in each line of data before the
+// index that comment returns for it. continued tells comment whether the
+// previous line ended with a backslash.
+func (p Params) expandLines(
+	data []byte, comment func(line []byte, continued bool) int) (
+	[]byte, error) {
+	var result bytes.Buffer
+	continued := false
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			end = len(data)
+		}
+		line := data[:end]
+		data = data[end:]
+		text := bytes.TrimRight(line, "\r\n")
+		c := comment(text, continued)
+		expanded, err := p.expand(text[:c])
+		if err != nil {
+			return nil, err
+		}
+		result.Write(expanded)
+		result.Write(line[c:])
+		continued = endsWithContinuation(string(text))
+	}
+	return result.Bytes(), nil
+}
+
+// yamlCommentStart returns the index of the '#' that starts a comment in
+// the yaml line or len(line) if it has no comment. A '#' starts a comment
+// at the start of the line or after white space, outside quoted scalars.
+func yamlCommentStart(line []byte) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\',
+			quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			// Skip the escaped character.
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return i
+		case (c == '\'' || c == '"') && startsScalar(line[:i]):
+			quote = c
+		}
+	}
+	return len(line)
+}
+
+// startsScalar reports whether a quote after before would start a quoted
+// scalar rather than be part of a plain one.
+func startsScalar(before []byte) bool {
+	before = bytes.TrimRight(before, " \t")
+	if len(before) == 0 {
+		return true
+	}
+	return bytes.IndexByte([]byte(":-[{,?"), before[len(before)-1]) >= 0
+}
+
+// expand returns data with its placeholders replaced.
+func (p Params) expand(data []byte) ([]byte, error) {
+	var result bytes.Buffer
+	for {
+		i := bytes.Index(data, []byte("${"))
+		if i < 0 {
+			result.Write(data)
+			return result.Bytes(), nil
+		}
+		if i > 0 && data[i-1] == '$' {
+			result.Write(data[:i-1])
+			result.WriteString("${")
+			data = data[i+2:]
+			continue
+		}
+		result.Write(data[:i])
+		end := bytes.IndexByte(data[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("Unterminated placeholder: %.20s", data[i:])
+		}
+		placeholder := string(data[i+2 : i+end])
+		data = data[i+end+1:]
+		name, def, hasDefault := placeholder, "", false
+		if j := strings.Index(placeholder, ":-"); j >= 0 {
+			name, def, hasDefault = placeholder[:j], placeholder[j+2:], true
+		}
+		if name == "" {
+			return nil, fmt.Errorf("Placeholder without a name: ${%s}", placeholder)
+		}
+		value, ok := p.Lookup(name)
+		switch {
+		case hasDefault && value == "":
+			value = def
+		case !ok:
+			return nil, fmt.Errorf("%s is not set", name)
+		}
+		result.WriteString(value)
+	}
+}
@@ -0,0 +1,92 @@
+package gooteltest
+
+import (
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	params := Params{"PERIOD": "5s", "NAME": "latency"}
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{
+			name: "values",
+			in:   "collectPeriod: ${PERIOD}\nmeter: ${METER:-opamp}\n",
+			want: "collectPeriod: 5s\nmeter: opamp\n",
+		},
+		{
+			name: "escape",
+			in:   "body: \"$${NAME}\"",
+			want: "body: \"${NAME}\"",
+		},
+		{
+			name: "commentLine",
+			in:   "# Set ${UNSET} to change\ncollectPeriod: ${PERIOD}\n",
+			want: "# Set ${UNSET} to change\ncollectPeriod: 5s\n",
+		},
+		{
+			name: "trailingComment",
+			in:   "collectPeriod: ${PERIOD} # or ${UNSET}\r\n",
+			want: "collectPeriod: 5s # or ${UNSET}\r\n",
+		},
+		{
+			name: "hashInQuotes",
+			in:   "body: \"#${NAME} \\\" #${NAME}\" # ${UNSET}\n",
+			want: "body: \"#latency \\\" #latency\" # ${UNSET}\n",
+		},
+		{
+			name: "hashInSingleQuotes",
+			in:   "body: 'it''s #${NAME}'\n",
+			want: "body: 'it''s #latency'\n",
+		},
+		{
+			name: "hashInWord",
+			in:   "name: a#${NAME}\n",
+			want: "name: a#latency\n",
+		},
+		{
+			name: "apostropheInPlainScalar",
+			in:   "description: it's ${NAME} # ${UNSET}\n",
+			want: "description: it's latency # ${UNSET}\n",
+		},
+		{
+			name: "unset",
+			in:   "meter: ${UNSET}\n",
+			err:  "UNSET is not set",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := params.Expand([]byte(tc.in))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Got error %v, want %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandProperties(t *testing.T) {
+	params := Params{"NAME": "latency"}
+	in := "# ${UNSET}\n  ! ${UNSET}\nmetric.name=${NAME} # not a comment\n" +
+		"description=first \\\n# ${NAME}\n"
+	want := "# ${UNSET}\n  ! ${UNSET}\nmetric.name=latency # not a comment\n" +
+		"description=first \\\n# latency\n"
+	got, err := params.ExpandProperties([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phase is one phase of a scenario, such as a ramp, a steady state, or a
+// spike. A phase plays its own value sets at its own collect period for
+// its duration.
+type Phase struct {
+	Name string `yaml:"name"`
+
+	// How long the phase lasts.
+	Duration time.Duration `yaml:"duration"`
+
+	// Overrides the collect period of the config for this phase.
+	CollectPeriod time.Duration `yaml:"collectPeriod,omitempty"`
+
+	// If set, values move linearly from where the previous phase left
+	// them to the values of this phase over this long at the start of the
+	// phase.
+	Ramp time.Duration `yaml:"ramp,omitempty"`
+
+	// The value sets played in this phase. They start from the first
+	// each time the phase starts and loop until it ends. Metrics missing
+	// from the first value set keep the values they had at the end of the
+	// previous phase. If empty, the value sets of the config are played.
+	ValueSets []MetricValueSet `yaml:"valueSets,omitempty"`
+}
+
+// Iterations returns the number of value sets sent in this phase. A
+// phase with no duration never ends.
+func (p *Phase) Iterations() int {
+	if p.Duration == 0 {
+		return 0
+	}
+	result := int(p.Duration / p.CollectPeriod)
+	if result < 1 {
+		result = 1
+	}
+	return result
+}
+
+// RampFraction returns how far along its ramp this phase is at the given
+// iteration within the phase: 1 means the ramp is over.
+func (p *Phase) RampFraction(iteration int) float64 {
+	steps := int(p.Ramp / p.CollectPeriod)
+	if steps < 1 || iteration >= steps {
+		return 1
+	}
+	return float64(iteration+1) / float64(steps)
+}
+
+// PlayPhases returns the phases to play. A config without phases has a
+// single phase that plays its value sets forever.
+func (c *Config) PlayPhases() []Phase {
+	if len(c.Phases) == 0 {
+		return []Phase{{
+			CollectPeriod: c.CollectPeriod,
+			ValueSets:     c.ValueSets,
+		}}
+	}
+	result := make([]Phase, len(c.Phases))
+	for i, phase := range c.Phases {
+		if len(phase.ValueSets) == 0 {
+			phase.ValueSets = c.ValueSets
+		}
+		result[i] = phase
+	}
+	return result
+}
+
+// ExportPeriod returns how often metrics are exported: the shortest
+// collect period of any phase, so that every value set played is
+// exported.
+func (c *Config) ExportPeriod() time.Duration {
+	result := c.CollectPeriod
+	for _, phase := range c.Phases {
+		if phase.CollectPeriod < result {
+			result = phase.CollectPeriod
+		}
+	}
+	return result
+}
+
+// NewPhaseEngines returns an Engine for each phase. Every Engine keeps
+// track of all the metrics of all the phases, so that the caller can
+// carry the values that one phase ends with into the next with
+// Engine.CarryFrom.
+func NewPhaseEngines(phases []Phase) []*Engine {
+	var names []string
+	seen := make(map[string]bool)
+	result := make([]*Engine, len(phases))
+	for i, phase := range phases {
+		for _, valueSet := range phase.ValueSets {
+			for _, v := range valueSet.ValueSet {
+				if !seen[v.Name] {
+					seen[v.Name] = true
+					names = append(names, v.Name)
+				}
+			}
+		}
+		result[i] = NewEngine(phase.ValueSets)
+	}
+	for _, engine := range result {
+		engine.AddMetrics(names...)
+	}
+	return result
+}
+
+// Ramp returns s with its value moved from from by fraction of the way to
+// its value. If the observations of s are just its value, they move too.
+func (s Sample) Ramp(from, fraction float64) Sample {
+	value := from + (s.Value-from)*fraction
+	if len(s.Observations) == 1 && s.Observations[0] == s.Value {
+		s.Observations = []float64{value}
+	}
+	s.Value = value
+	return s
+}
+
+func checkPhases(config *Config, metricTypes map[string]string) error {
+	names := make(map[string]bool)
+	for _, phase := range config.Phases {
+		if phase.Name == "" {
+			return errors.New("phases must have a name")
+		}
+		if names[phase.Name] {
+			return fmt.Errorf("Duplicate phase: %s", phase.Name)
+		}
+		names[phase.Name] = true
+		if phase.Duration <= 0 {
+			return fmt.Errorf(
+				"Phase '%s' must have a positive duration", phase.Name)
+		}
+		if phase.CollectPeriod <= 0 {
+			return fmt.Errorf(
+				"Phase '%s' collectPeriod must be a positive duration",
+				phase.Name)
+		}
+		if phase.Ramp < 0 || phase.Ramp > phase.Duration {
+			return fmt.Errorf(
+				"Phase '%s' ramp must be between 0 and its duration",
+				phase.Name)
+		}
+		if len(phase.ValueSets) == 0 && len(config.ValueSets) == 0 &&
+			len(config.Metrics) > 0 {
+			return fmt.Errorf("Phase '%s' has no value sets", phase.Name)
+		}
+		if err := checkValueSets(phase.ValueSets, metricTypes); err != nil {
+			return fmt.Errorf("Phase '%s': %v", phase.Name, err)
+		}
+	}
+	return nil
+}
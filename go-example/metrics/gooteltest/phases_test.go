@@ -0,0 +1,59 @@
+package gooteltest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPhaseEnginesCarryValues(t *testing.T) {
+	phases := []Phase{
+		{
+			Name:          "a",
+			Duration:      3 * time.Minute,
+			CollectPeriod: time.Minute,
+			ValueSets: []MetricValueSet{
+				{ValueSet: []MetricValue{{Name: "x", Value: 1}}},
+				{ValueSet: []MetricValue{
+					{Name: "x", Value: 2},
+					{Name: "y", Value: 7},
+				}},
+			},
+		},
+		{
+			Name:          "b",
+			Duration:      2 * time.Minute,
+			CollectPeriod: time.Minute,
+			ValueSets: []MetricValueSet{
+				{ValueSet: []MetricValue{{Name: "y", Value: 20}}},
+				{ValueSet: []MetricValue{{Name: "x", Value: 5}}},
+			},
+		},
+	}
+	engines := NewPhaseEngines(phases)
+	var got [][2]float64
+	for i := 0; i < 3; i++ {
+		// Play each phase the way oteltester does.
+		p := i % len(phases)
+		if i > 0 {
+			prev := (p + len(phases) - 1) % len(phases)
+			engines[p].CarryFrom(engines[prev], phases[prev].Iterations())
+			engines[p].Reset()
+		}
+		for k := 0; k < phases[p].Iterations(); k++ {
+			got = append(got, [2]float64{
+				engines[p].NextValue("x"), engines[p].NextValue("y")})
+		}
+	}
+	want := [][2]float64{
+		// a: y has no value yet when the value sets loop.
+		{1, 0}, {2, 7}, {1, 0},
+		// b: x carries on from 1, where a ended, not 2.
+		{1, 20}, {5, 20},
+		// a again: y carries on from 20, where b ended.
+		{1, 20}, {2, 7}, {1, 20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
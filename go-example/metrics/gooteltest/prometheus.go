@@ -0,0 +1,277 @@
+package gooteltest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promSample is one sample line of a Prometheus text exposition.
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// promHistogram gathers the _bucket and _count samples of one histogram
+// series.
+type promHistogram struct {
+	labels  map[string]string
+	buckets map[float64]float64
+	count   float64
+}
+
+// AddPrometheus adds one snapshot in the Prometheus text exposition format
+// taken at time t. Every point gets the resource attributes res, which may
+// be nil. Counters become cumulative sums, gauges and untyped metrics
+// become gauges, and histograms become cumulative histograms. Summaries
+// can't be replayed, so they are skipped. Timestamps on sample lines are
+// ignored; all points are added at t. HELP and OpenMetrics UNIT lines give
+// the description and unit of metrics.
+//
+// A malformed line makes AddPrometheus return an error right away.
+// Otherwise it adds every point it can; if it skips any, it returns an
+// error describing the first one.
+func (b *ConfigBuilder) AddPrometheus(
+	r io.Reader, t time.Time, res map[string]string) error {
+	types := make(map[string]string)
+	metadata := make(map[string]builderMetadata)
+	var samples []promSample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			if len(fields) >= 3 && (fields[1] == "HELP" || fields[1] == "UNIT") {
+				name := fields[2]
+				text := strings.TrimSpace(line[1:])
+				text = strings.TrimSpace(text[len(fields[1]):])
+				text = strings.TrimSpace(text[len(name):])
+				m := metadata[name]
+				if fields[1] == "HELP" {
+					m.description = promHelpReplacer.Replace(text)
+				} else {
+					m.unit = text
+				}
+				metadata[name] = m
+			}
+			continue
+		}
+		sample, err := parsePromSample(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for name, m := range metadata {
+		b.SetMetadata(name, m.unit, m.description)
+	}
+
+	var firstErr error
+	skip := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	histograms := make(map[string]*promHistogram)
+	var histogramKeys []string
+	for _, s := range samples {
+		family, suffix := promFamily(s.name, types)
+		id := SeriesID{Resource: res, Metric: s.name, Attributes: s.labels}
+		switch types[family] {
+		case "counter":
+			b.AddSum(id, t, s.value, CumulativeAggregationSelector, true)
+		case "histogram":
+			if suffix == "_sum" {
+				// Sums are approximated from the buckets on replay.
+				continue
+			}
+			labels := copyAttributes(s.labels)
+			le := labels["le"]
+			delete(labels, "le")
+			key := family + "\x00" + attributesKey(labels)
+			h, ok := histograms[key]
+			if !ok {
+				h = &promHistogram{
+					labels:  labels,
+					buckets: make(map[float64]float64),
+				}
+				histograms[key] = h
+				histogramKeys = append(histogramKeys, key)
+			}
+			switch suffix {
+			case "_count":
+				h.count = s.value
+			case "_bucket":
+				bound, err := parsePromFloat(le)
+				if err != nil {
+					skip(fmt.Errorf(
+						"skipped bucket of '%s' with invalid le '%s'", family, le))
+					continue
+				}
+				h.buckets[bound] = s.value
+			}
+		case "summary":
+			if suffix == "" {
+				skip(fmt.Errorf("skipped summary '%s'", family))
+			}
+		default:
+			b.AddGauge(id, t, s.value)
+		}
+	}
+	for _, key := range histogramKeys {
+		h := histograms[key]
+		family := strings.SplitN(key, "\x00", 2)[0]
+		boundaries, counts := h.bucketCounts()
+		err := b.AddHistogram(
+			SeriesID{Resource: res, Metric: family, Attributes: h.labels},
+			t,
+			CumulativeAggregationSelector,
+			boundaries,
+			counts)
+		if err != nil {
+			skip(err)
+		}
+	}
+	return firstErr
+}
+
+// promHelpReplacer undoes the escapes in HELP lines.
+var promHelpReplacer = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+// bucketCounts converts the cumulative le buckets of h to boundaries and
+// per bucket counts.
+func (h *promHistogram) bucketCounts() ([]float64, []uint64) {
+	var boundaries []float64
+	for bound := range h.buckets {
+		if !math.IsInf(bound, 1) {
+			boundaries = append(boundaries, bound)
+		}
+	}
+	sort.Float64s(boundaries)
+	total := h.count
+	if inf, ok := h.buckets[math.Inf(1)]; ok {
+		total = inf
+	}
+	counts := make([]uint64, len(boundaries)+1)
+	var below float64
+	for i, bound := range boundaries {
+		cumulative := h.buckets[bound]
+		counts[i] = uint64(math.Max(cumulative-below, 0))
+		below = cumulative
+	}
+	counts[len(boundaries)] = uint64(math.Max(total-below, 0))
+	return boundaries, counts
+}
+
+// promFamily returns the metric family that the sample named name belongs
+// to and the suffix that was removed to find it.
+func promFamily(name string, types map[string]string) (string, string) {
+	if _, ok := types[name]; ok {
+		return name, ""
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+		family := strings.TrimSuffix(name, suffix)
+		if family != name {
+			if _, ok := types[family]; ok {
+				return family, suffix
+			}
+		}
+	}
+	return name, ""
+}
+
+// parsePromSample parses a line like 'name{a="b"} 1.5 1600000000000'.
+func parsePromSample(line string) (promSample, error) {
+	var result promSample
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return result, fmt.Errorf("malformed sample '%s'", line)
+	}
+	result.name = line[:end]
+	rest := line[end:]
+	if rest[0] == '{' {
+		labels, remaining, err := parsePromLabels(rest[1:])
+		if err != nil {
+			return result, err
+		}
+		result.labels = labels
+		rest = remaining
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return result, fmt.Errorf("malformed sample '%s'", line)
+	}
+	value, err := parsePromFloat(fields[0])
+	if err != nil {
+		return result, fmt.Errorf("invalid value '%s'", fields[0])
+	}
+	result.value = value
+	return result, nil
+}
+
+// parsePromLabels parses the labels after the opening brace. It returns
+// the labels and what follows the closing brace.
+func parsePromLabels(s string) (map[string]string, string, error) {
+	var labels map[string]string
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || eq+1 >= len(s) || s[eq+1] != '"' {
+			return nil, "", fmt.Errorf("malformed labels '%s'", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("unterminated label value for '%s'", key)
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = value.String()
+		s = s[i+1:]
+	}
+}
+
+func parsePromFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
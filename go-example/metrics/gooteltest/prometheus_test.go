@@ -0,0 +1,220 @@
+package gooteltest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// promConfig returns the Config built from snapshots, taken a minute
+// apart.
+func promConfig(t *testing.T, snapshots ...string) *Config {
+	t.Helper()
+	b := NewConfigBuilder(time.Minute)
+	start := time.Unix(1700000000, 0)
+	for i, text := range snapshots {
+		err := b.AddPrometheus(
+			strings.NewReader(text),
+			start.Add(time.Duration(i)*time.Minute),
+			nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := b.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestAddPrometheusMetadata(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want MetricInfo
+	}{
+		{
+			name: "counter",
+			text: "# TYPE requests counter\nrequests_total 5\n",
+			want: MetricInfo{
+				Name:        "requests_total",
+				Type:        MetricTypeSum,
+				Temporality: CumulativeAggregationSelector,
+			},
+		},
+		{
+			name: "gauge",
+			text: "# TYPE temp gauge\ntemp 21\n",
+			want: MetricInfo{Name: "temp", Type: MetricTypeGauge},
+		},
+		{
+			name: "untyped",
+			text: "temp 21\n",
+			want: MetricInfo{Name: "temp", Type: MetricTypeGauge},
+		},
+		{
+			name: "help",
+			text: "# HELP temp Room \\\\ temperature\\nin Celsius.\ntemp 21\n",
+			want: MetricInfo{
+				Name:        "temp",
+				Type:        MetricTypeGauge,
+				Description: "Room \\ temperature\nin Celsius.",
+			},
+		},
+		{
+			name: "unit",
+			text: "# TYPE temp gauge\n# UNIT temp Cel\ntemp 21\n",
+			want: MetricInfo{Name: "temp", Type: MetricTypeGauge, Unit: "Cel"},
+		},
+		{
+			name: "invalidUnit",
+			text: "# UNIT temp celsius\ntemp 21\n",
+			want: MetricInfo{Name: "temp", Type: MetricTypeGauge},
+		},
+		{
+			name: "labels",
+			text: "temp{room=\"kitchen\",floor=\"1\\\"st\\\"\"} 21 1700000000000\n",
+			want: MetricInfo{
+				Name: "temp",
+				Type: MetricTypeGauge,
+				Attributes: map[string]string{
+					"room":  "kitchen",
+					"floor": "1\"st\"",
+				},
+			},
+		},
+		{
+			name: "histogram",
+			text: `# TYPE latency histogram
+latency_bucket{le="1"} 1
+latency_bucket{le="2"} 3
+latency_bucket{le="+Inf"} 4
+latency_sum 5.5
+latency_count 4
+`,
+			want: MetricInfo{
+				Name:        "latency",
+				Type:        MetricTypeHistogram,
+				Temporality: CumulativeAggregationSelector,
+				Boundaries:  []float64{1, 2},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := promConfig(t, tc.text)
+			if len(config.Metrics) != 1 {
+				t.Fatalf("Got metrics %+v, want one", config.Metrics)
+			}
+			if got := config.Metrics[0]; !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddPrometheusValues(t *testing.T) {
+	snapshot := func(requests, temp, le1, le2, inf string) string {
+		return `# TYPE requests counter
+requests_total ` + requests + `
+# TYPE temp gauge
+temp ` + temp + `
+# TYPE latency histogram
+latency_bucket{le="1"} ` + le1 + `
+latency_bucket{le="2"} ` + le2 + `
+latency_bucket{le="+Inf"} ` + inf + `
+latency_count ` + inf + `
+`
+	}
+	config := promConfig(t,
+		snapshot("10", "21", "1", "3", "4"),
+		snapshot("15", "22", "2", "5", "7"))
+	// Cumulative points become deltas, and the first only sets the
+	// baseline.
+	want := []map[string]MetricValue{
+		{
+			"requests_total": {Name: "requests_total"},
+			"temp":           {Name: "temp", Value: 21},
+			"latency":        {Name: "latency", Observations: Observations{}},
+		},
+		{
+			"requests_total": {Name: "requests_total", Value: 5},
+			"temp":           {Name: "temp", Value: 22},
+			"latency": {
+				Name:         "latency",
+				Observations: Observations{0.5, 1.5, 2.5},
+			},
+		},
+	}
+	if len(config.ValueSets) != len(want) {
+		t.Fatalf("Got %d value sets, want %d", len(config.ValueSets), len(want))
+	}
+	for i, values := range want {
+		got := make(map[string]MetricValue)
+		for _, v := range config.ValueSets[i].ValueSet {
+			got[v.Name] = v
+		}
+		if !reflect.DeepEqual(got, values) {
+			t.Errorf("Value set %d: got %+v, want %+v", i, got, values)
+		}
+	}
+}
+
+func TestAddPrometheusMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "noValue",
+			text: "temp 21\ntemp\n",
+			want: "line 2: malformed sample 'temp'",
+		},
+		{
+			name: "noName",
+			text: "{a=\"b\"} 1\n",
+			want: "line 1: malformed sample",
+		},
+		{
+			name: "badValue",
+			text: "temp warm\n",
+			want: "line 1: invalid value 'warm'",
+		},
+		{
+			name: "extraField",
+			text: "temp 21 1700000000000 x\n",
+			want: "line 1: malformed sample",
+		},
+		{
+			name: "unquotedLabel",
+			text: "temp{room=kitchen} 21\n",
+			want: "line 1: malformed labels",
+		},
+		{
+			name: "unterminatedLabel",
+			text: "temp{room=\"kitchen} 21\n",
+			want: "line 1: unterminated label value for 'room'",
+		},
+		{
+			name: "summary",
+			text: "# TYPE rpc summary\nrpc{quantile=\"0.5\"} 1\nrpc 1\n",
+			want: "skipped summary 'rpc'",
+		},
+		{
+			name: "badLe",
+			text: "# TYPE latency histogram\nlatency_bucket{le=\"x\"} 1\n",
+			want: "skipped bucket of 'latency' with invalid le 'x'",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewConfigBuilder(time.Minute)
+			err := b.AddPrometheus(
+				strings.NewReader(tc.text), time.Unix(1700000000, 0), nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package gooteltest
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const week = 7 * 24 * time.Hour
+
+// Seasonal generates values that follow daily and weekly cycles with
+// noise, like the traffic of a real service. The value at time t is
+//
+//	base + daily*cos(2π(timeOfDay-dailyPeak)/24h)
+//	     + weekly*cos(2π(timeOfWeek-weeklyPeak)/7d) + noise*N(0, 1)
+//
+// where times of day and week are in UTC and weeks start on Monday.
+type Seasonal struct {
+
+	// The mean value.
+	Base float64 `yaml:"base"`
+
+	// The amplitude of the daily cycle.
+	Daily float64 `yaml:"daily,omitempty"`
+
+	// The time of day in UTC when the daily cycle peaks, such as 14h.
+	DailyPeak time.Duration `yaml:"dailyPeak,omitempty"`
+
+	// The amplitude of the weekly cycle.
+	Weekly float64 `yaml:"weekly,omitempty"`
+
+	// The time since Monday 00:00 UTC when the weekly cycle peaks, such as
+	// 60h for Wednesday noon.
+	WeeklyPeak time.Duration `yaml:"weeklyPeak,omitempty"`
+
+	// The standard deviation of the gaussian noise added to each value.
+	Noise float64 `yaml:"noise,omitempty"`
+
+	// How many times faster than real time the cycles run, starting from
+	// the time the run starts. Set to 24 to play a day each hour. Default
+	// is 1.
+	Speed float64 `yaml:"speed,omitempty"`
+
+	// If set, values are never less than this.
+	Min *float64 `yaml:"min,omitempty"`
+}
+
+// Value returns the value at time t of a run that started at start. r
+// generates the noise.
+func (s *Seasonal) Value(start, t time.Time, r *rand.Rand) float64 {
+	speed := s.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	t = start.Add(time.Duration(float64(t.Sub(start)) * speed)).UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	timeOfDay := t.Sub(midnight)
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	timeOfWeek := time.Duration(daysSinceMonday)*24*time.Hour + timeOfDay
+	result := s.Base +
+		s.Daily*cycle(timeOfDay-s.DailyPeak, 24*time.Hour) +
+		s.Weekly*cycle(timeOfWeek-s.WeeklyPeak, week)
+	if s.Noise != 0 {
+		result += s.Noise * r.NormFloat64()
+	}
+	if s.Min != nil && result < *s.Min {
+		result = *s.Min
+	}
+	return result
+}
+
+func cycle(d, period time.Duration) float64 {
+	return math.Cos(2 * math.Pi * float64(d) / float64(period))
+}
+
+func checkSeasonal(s *Seasonal) error {
+	if s.Noise < 0 {
+		return errors.New("seasonal noise must not be negative")
+	}
+	if s.Speed < 0 {
+		return errors.New("seasonal speed must not be negative")
+	}
+	return nil
+}
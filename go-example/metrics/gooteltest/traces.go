@@ -0,0 +1,192 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	SpanKindInternal = "internal"
+	SpanKindServer   = "server"
+	SpanKindClient   = "client"
+	SpanKindProducer = "producer"
+	SpanKindConsumer = "consumer"
+)
+
+var spanKindNames = map[string]bool{
+	SpanKindInternal: true,
+	SpanKindServer:   true,
+	SpanKindClient:   true,
+	SpanKindProducer: true,
+	SpanKindConsumer: true,
+}
+
+// The most and fewest traces per second a TraceScenario may send. The
+// fewest is one an hour.
+const (
+	MaxTraceRate = 10000
+	MinTraceRate = 1.0 / 3600
+)
+
+// TraceScenario describes a tree of spans that gets sent over and over
+// at a fixed rate.
+type TraceScenario struct {
+
+	// The name of the scenario. Links use it to refer to spans in other
+	// scenarios.
+	Name string `yaml:"name"`
+
+	// How many traces to send per second, from MinTraceRate to
+	// MaxTraceRate.
+	Rate float64 `yaml:"rate"`
+
+	// The root span of each trace.
+	Root SpanInfo `yaml:"root"`
+}
+
+// SpanInfo describes one span in a TraceScenario and its children.
+type SpanInfo struct {
+	Name string `yaml:"name"`
+
+	// The service.name of the service sending this span. If it names one
+	// of the services in Config, the span gets that service's resource.
+	// Default is the service of the parent span, or the default service
+	// for the root span.
+	Service string `yaml:"service,omitempty"`
+
+	// One of 'internal', 'server', 'client', 'producer', or 'consumer'.
+	// Default is 'internal'.
+	Kind string `yaml:"kind,omitempty"`
+
+	// How long after the start of the parent span this span starts.
+	Offset time.Duration `yaml:"offset,omitempty"`
+
+	// How long this span lasts.
+	Duration time.Duration `yaml:"duration"`
+
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+	Events     []SpanEvent       `yaml:"events,omitempty"`
+	Links      []SpanLink        `yaml:"links,omitempty"`
+
+	// If set, the span has an error status with this description.
+	Error string `yaml:"error,omitempty"`
+
+	Children []SpanInfo `yaml:"children,omitempty"`
+}
+
+// SpanEvent is an event that happens Offset after the start of its span.
+type SpanEvent struct {
+	Name       string            `yaml:"name"`
+	Offset     time.Duration     `yaml:"offset,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
+// SpanLink links a span to the most recently sent span with the name Span
+// in the scenario Scenario. If Scenario is omitted, it means the scenario
+// containing the link.
+type SpanLink struct {
+	Scenario   string            `yaml:"scenario,omitempty"`
+	Span       string            `yaml:"span"`
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
+func (s *SpanInfo) fixDefaults() {
+	if s.Kind == "" {
+		s.Kind = SpanKindInternal
+	}
+	for i := range s.Children {
+		s.Children[i].fixDefaults()
+	}
+}
+
+// spanNames adds the names of s and all its descendants to names.
+func (s *SpanInfo) spanNames(names map[string]struct{}) {
+	names[s.Name] = struct{}{}
+	for i := range s.Children {
+		s.Children[i].spanNames(names)
+	}
+}
+
+func checkTraces(config *Config) error {
+	spansByScenario := make(map[string]map[string]struct{})
+	for _, scenario := range config.Traces {
+		if scenario.Name == "" {
+			return errors.New("traces must have a name")
+		}
+		if _, ok := spansByScenario[scenario.Name]; ok {
+			return fmt.Errorf("Duplicate trace: %s", scenario.Name)
+		}
+		if scenario.Rate <= 0 || math.IsNaN(scenario.Rate) {
+			return fmt.Errorf(
+				"Trace '%s' needs a positive rate", scenario.Name)
+		}
+		if scenario.Rate < MinTraceRate {
+			return fmt.Errorf(
+				"Trace '%s' rate must be at least one per hour", scenario.Name)
+		}
+		if scenario.Rate > MaxTraceRate {
+			return fmt.Errorf(
+				"Trace '%s' rate must be at most %d per second",
+				scenario.Name,
+				MaxTraceRate)
+		}
+		names := make(map[string]struct{})
+		scenario.Root.spanNames(names)
+		spansByScenario[scenario.Name] = names
+	}
+	for _, scenario := range config.Traces {
+		err := checkSpan(&scenario.Root, scenario.Name, spansByScenario)
+		if err != nil {
+			return fmt.Errorf("Trace '%s': %v", scenario.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkSpan(
+	span *SpanInfo,
+	scenario string,
+	spansByScenario map[string]map[string]struct{}) error {
+	if span.Name == "" {
+		return errors.New("spans must have a name")
+	}
+	if !spanKindNames[span.Kind] {
+		return fmt.Errorf("Unknown span kind: %s", span.Kind)
+	}
+	if span.Offset < 0 || span.Duration < 0 {
+		return fmt.Errorf(
+			"Span '%s' cannot have a negative offset or duration", span.Name)
+	}
+	for _, event := range span.Events {
+		if event.Name == "" {
+			return fmt.Errorf("Span '%s' has an event with no name", span.Name)
+		}
+		if event.Offset < 0 {
+			return fmt.Errorf(
+				"Span '%s' has an event with a negative offset", span.Name)
+		}
+	}
+	for _, link := range span.Links {
+		target := link.Scenario
+		if target == "" {
+			target = scenario
+		}
+		names, ok := spansByScenario[target]
+		if !ok {
+			return fmt.Errorf(
+				"Span '%s' links to unknown trace: %s", span.Name, target)
+		}
+		if _, ok := names[link.Span]; !ok {
+			return fmt.Errorf(
+				"Span '%s' links to unknown span: %s", span.Name, link.Span)
+		}
+	}
+	for i := range span.Children {
+		if err := checkSpan(&span.Children[i], scenario, spansByScenario); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package gooteltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadConfigTraceRate(t *testing.T) {
+	for _, tc := range []struct {
+		rate string
+		want string
+	}{
+		{rate: "0.5"},
+		{rate: "10000"},
+		{rate: "0.0003"},
+		{rate: "0", want: "Trace 'checkout' needs a positive rate"},
+		{
+			rate: "1e-10",
+			want: "Trace 'checkout' rate must be at least one per hour",
+		},
+		{
+			rate: "0.0002",
+			want: "Trace 'checkout' rate must be at least one per hour",
+		},
+		{rate: ".nan", want: "Trace 'checkout' needs a positive rate"},
+		{
+			rate: "10001",
+			want: "Trace 'checkout' rate must be at most 10000 per second",
+		},
+		{
+			rate: "1e12",
+			want: "Trace 'checkout' rate must be at most 10000 per second",
+		},
+	} {
+		t.Run(tc.rate, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(`
+traces:
+- name: checkout
+  rate: ` + tc.rate + `
+  root:
+    name: GET /checkout
+    duration: 10ms
+`))
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.want {
+				t.Errorf("Got error %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxUnitLength is the longest unit that OpenTelemetry allows.
+const MaxUnitLength = 63
+
+// CheckUnit returns an error if unit is not a unit of The Unified Code
+// for Units of Measure (UCUM), such as 'ms', 'By/s', 'kg.m/s2', '10*3',
+// '1', '{request}', or '%'. Besides the syntax, CheckUnit checks each
+// unit against the common UCUM units in ucumAtoms and ucumMetricAtoms,
+// the latter with or without a prefix. The empty unit is valid.
+func CheckUnit(unit string) error {
+	if unit == "" {
+		return nil
+	}
+	if len(unit) > MaxUnitLength {
+		return fmt.Errorf("unit longer than %d characters", MaxUnitLength)
+	}
+	for i := 0; i < len(unit); i++ {
+		if unit[i] <= ' ' || unit[i] > '~' {
+			return fmt.Errorf(
+				"unit must be ASCII without spaces: %q", unit)
+		}
+	}
+	p := ucumParser{s: unit}
+	if p.peek() == '/' {
+		p.pos++
+	}
+	if err := p.term(); err != nil {
+		return fmt.Errorf("invalid unit %q: %v", unit, err)
+	}
+	if p.pos < len(p.s) {
+		return fmt.Errorf(
+			"invalid unit %q: unexpected '%c'", unit, p.s[p.pos])
+	}
+	return nil
+}
+
+// ucumMetricAtoms are the UCUM units that take prefixes, such as the 'k'
+// of 'kg' or the 'Mi' of 'MiBy'.
+var ucumMetricAtoms = map[string]bool{
+	// Base units.
+	"m": true, "g": true, "s": true, "rad": true, "K": true, "C": true,
+	"cd": true,
+	// SI units.
+	"mol": true, "sr": true, "Hz": true, "N": true, "Pa": true, "J": true,
+	"W": true, "A": true, "V": true, "F": true, "Ohm": true, "S": true,
+	"Wb": true, "Cel": true, "T": true, "H": true, "lm": true, "lx": true,
+	"Bq": true, "Gy": true, "Sv": true,
+	// Other metric units.
+	"l": true, "L": true, "ar": true, "t": true, "bar": true, "u": true,
+	"eV": true, "pc": true, "cal": true, "G": true, "dyn": true,
+	"erg": true, "P": true, "St": true,
+	// Units of information.
+	"bit": true, "By": true, "Bd": true,
+}
+
+// ucumAtoms are the UCUM units that don't take prefixes.
+var ucumAtoms = map[string]bool{
+	"%": true, "min": true, "h": true, "d": true, "wk": true, "mo": true,
+	"a": true, "deg": true, "'": true, "''": true, "gon": true,
+	"[pi]": true, "[ppth]": true, "[ppm]": true, "[ppb]": true,
+	"[pptr]": true, "[in_i]": true, "[ft_i]": true, "[yd_i]": true,
+	"[mi_i]": true, "[lb_av]": true, "[oz_av]": true, "[degF]": true,
+	"[psi]": true, "[iU]": true, "[IU]": true, "[HP]": true,
+	"[car_m]": true, "[gal_us]": true,
+}
+
+// ucumPrefixes are the UCUM prefixes, decimal and binary.
+var ucumPrefixes = []string{
+	"Y", "Z", "E", "P", "T", "G", "M", "k", "h", "da", "d", "c", "m", "u",
+	"n", "p", "f", "a", "z", "y", "Ki", "Mi", "Gi", "Ti",
+}
+
+// knownUnit returns true if atom is one of ucumAtoms or one of
+// ucumMetricAtoms with or without a prefix.
+func knownUnit(atom string) bool {
+	if ucumAtoms[atom] || ucumMetricAtoms[atom] {
+		return true
+	}
+	for _, prefix := range ucumPrefixes {
+		if strings.HasPrefix(atom, prefix) &&
+			ucumMetricAtoms[atom[len(prefix):]] {
+			return true
+		}
+	}
+	return false
+}
+
+// ucumParser parses the UCUM grammar:
+//
+//	term := component (('.' | '/') component)*
+//	component := annotatable annotation? | annotation | factor | '(' term ')'
+//	annotatable := simpleUnit exponent?
+type ucumParser struct {
+	s   string
+	pos int
+}
+
+func (p *ucumParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *ucumParser) term() error {
+	for {
+		if err := p.component(); err != nil {
+			return err
+		}
+		if c := p.peek(); c != '.' && c != '/' {
+			return nil
+		}
+		p.pos++
+	}
+}
+
+func (p *ucumParser) component() error {
+	switch c := p.peek(); {
+	case c == 0:
+		return errors.New("missing unit at end")
+	case c == '(':
+		p.pos++
+		if err := p.term(); err != nil {
+			return err
+		}
+		if p.peek() != ')' {
+			return errors.New("missing ')'")
+		}
+		p.pos++
+		return nil
+	case c == '{':
+		return p.annotation()
+	case c >= '0' && c <= '9':
+		p.digits()
+		// 10* and 10^ are units that take exponents. Other numbers are
+		// factors.
+		if c := p.peek(); c == '*' || c == '^' {
+			p.pos++
+			p.exponent()
+		}
+		return nil
+	}
+	if err := p.simpleUnit(); err != nil {
+		return err
+	}
+	p.exponent()
+	if p.peek() == '{' {
+		return p.annotation()
+	}
+	return nil
+}
+
+func (p *ucumParser) simpleUnit() error {
+	start := p.pos
+loop:
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '[':
+			end := p.pos + 1
+			for end < len(p.s) && p.s[end] != ']' && p.s[end] != '[' {
+				end++
+			}
+			if end == len(p.s) || p.s[end] != ']' {
+				return errors.New("missing ']'")
+			}
+			p.pos = end
+		case c == ']':
+			return errors.New("unexpected ']'")
+		case c == '.', c == '/', c == '(', c == ')', c == '{', c == '}',
+			c == '+', c == '-', c >= '0' && c <= '9':
+			break loop
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return fmt.Errorf("unexpected '%c'", p.peek())
+	}
+	if atom := p.s[start:p.pos]; !knownUnit(atom) {
+		return fmt.Errorf("unknown unit '%s'", atom)
+	}
+	return nil
+}
+
+func (p *ucumParser) exponent() {
+	start := p.pos
+	if c := p.peek(); c == '+' || c == '-' {
+		p.pos++
+	}
+	if p.digits() == 0 {
+		p.pos = start
+	}
+}
+
+func (p *ucumParser) digits() int {
+	start := p.pos
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	return p.pos - start
+}
+
+func (p *ucumParser) annotation() error {
+	p.pos++
+	for p.pos < len(p.s) && p.s[p.pos] != '}' {
+		if p.s[p.pos] == '{' {
+			return errors.New("unexpected '{' in annotation")
+		}
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return errors.New("missing '}'")
+	}
+	p.pos++
+	return nil
+}
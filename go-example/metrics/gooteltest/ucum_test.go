@@ -0,0 +1,71 @@
+package gooteltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckUnit(t *testing.T) {
+	for _, unit := range []string{
+		"",
+		"By/s",
+		"ms",
+		"{requests}",
+		"1",
+		"%",
+		"kg",
+		"MiBy",
+		"daL",
+		"us",
+		"Cel",
+		"min",
+		"kg.m/s2",
+		"m-1",
+		"s+2",
+		"/s",
+		"10*3",
+		"10^-6.m",
+		"{packet}/s",
+		"By{transmitted}",
+		"(m.s)/kg",
+		"[in_i]",
+		"[ppm]",
+	} {
+		if err := CheckUnit(unit); err != nil {
+			t.Errorf("CheckUnit(%q): %v", unit, err)
+		}
+	}
+}
+
+func TestCheckUnitInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		unit string
+		want string
+	}{
+		{unit: "requests", want: "unknown unit 'requests'"},
+		{unit: "seconds", want: "unknown unit 'seconds'"},
+		{unit: "kmin", want: "unknown unit 'kmin'"},
+		{unit: "[foo]", want: "unknown unit '[foo]'"},
+		{unit: "m^2", want: "unknown unit 'm^'"},
+		{unit: "{requests", want: "missing '}'"},
+		{unit: "{a{b}}", want: "unexpected '{' in annotation"},
+		{unit: "requests}", want: "unknown unit 'requests'"},
+		{unit: "By}", want: "unexpected '}'"},
+		{unit: "[in_i", want: "missing ']'"},
+		{unit: "(m/s", want: "missing ')'"},
+		{unit: "m/", want: "missing unit at end"},
+		{unit: "m+", want: "unexpected '+'"},
+		{unit: "s2-", want: "unexpected '-'"},
+		{unit: "m-x", want: "unexpected '-'"},
+		{unit: "m s", want: "without spaces"},
+		{unit: "µs", want: "must be ASCII"},
+		{unit: strings.Repeat("m", MaxUnitLength+1), want: "longer than"},
+	} {
+		t.Run(tc.unit, func(t *testing.T) {
+			err := CheckUnit(tc.unit)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
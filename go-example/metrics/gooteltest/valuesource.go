@@ -0,0 +1,235 @@
+package gooteltest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ValueSourceJSONL = "jsonl"
+	ValueSourceCSV   = "csv"
+)
+
+// ValueSourceFormat returns the format of the value source at path from
+// its extension: 'csv' for .csv files and 'jsonl' for everything else.
+func ValueSourceFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ValueSourceCSV
+	}
+	return ValueSourceJSONL
+}
+
+// ValueSetStream reads the MetricValueSets of a value source one at a
+// time so that value sources much larger than memory can be played. When
+// it reaches the end of the file, it seeks back to the start. Like
+// ValueSets in a config, a metric missing from a value set keeps its
+// value from the previous value set.
+//
+// A JSON Lines value source has one value set per line, written like the
+// value sets of a config:
+//
+//	{"valueSet": [{"name": "foo", "value": 3}, {"name": "bar", "value": 5}]}
+//
+// A CSV value source has a header row of metric names followed by one row
+// per value set. Empty cells leave the metric unchanged. A histogram cell
+// may list several observations separated by ';'.
+//
+// ValueSetStream instances are not safe to use with multiple goroutines.
+type ValueSetStream struct {
+	f           *os.File
+	format      string
+	metricTypes map[string]string
+
+	r      *bufio.Reader
+	csv    *csv.Reader
+	header []string
+
+	// The index of the next value set in the file.
+	index int
+
+	// The current value of each metric.
+	current map[string]MetricValue
+}
+
+// OpenValueSource opens the ValueSource of this config.
+func (c *Config) OpenValueSource() (*ValueSetStream, error) {
+	if c.ValueSource == "" {
+		return nil, errors.New("config has no valueSource")
+	}
+	f, err := os.Open(c.ValueSource)
+	if err != nil {
+		return nil, err
+	}
+	result := &ValueSetStream{
+		f:           f,
+		format:      ValueSourceFormat(c.ValueSource),
+		metricTypes: make(map[string]string),
+	}
+	for _, m := range c.Metrics {
+		result.metricTypes[m.Name] = m.Type
+	}
+	if err := result.rewind(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return result, nil
+}
+
+// Close closes the underlying file.
+func (s *ValueSetStream) Close() error {
+	return s.f.Close()
+}
+
+// Index returns the 0 based index in the file of the value set that the
+// last call to Next returned.
+func (s *ValueSetStream) Index() int {
+	return s.index - 1
+}
+
+// Position returns the index in the file of the value set that the next
+// call to Next returns.
+func (s *ValueSetStream) Position() int {
+	return s.index
+}
+
+// Seek makes the next call to Next return the value set at the given
+// index in the file, carrying forward values from the value sets before
+// it. Indexes past the end of the file wrap around.
+func (s *ValueSetStream) Seek(index int) error {
+	if err := s.rewind(); err != nil {
+		return err
+	}
+	for i := 0; i < index; i++ {
+		if _, err := s.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next returns the value of each metric in the next value set.
+func (s *ValueSetStream) Next() (map[string]MetricValue, error) {
+	valueSet, err := s.read()
+	if err == io.EOF {
+		if s.index == 0 {
+			return nil, fmt.Errorf("%s has no value sets", s.f.Name())
+		}
+		if err := s.rewind(); err != nil {
+			return nil, err
+		}
+		valueSet, err = s.read()
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s: value set %d: %v", s.f.Name(), s.index+1, err)
+	}
+	if err := checkValueSets(
+		[]MetricValueSet{valueSet}, s.metricTypes); err != nil {
+		return nil, fmt.Errorf(
+			"%s: value set %d: %v", s.f.Name(), s.index+1, err)
+	}
+	for _, v := range valueSet.ValueSet {
+		s.current[v.Name] = v
+	}
+	s.index++
+	return s.current, nil
+}
+
+// rewind goes back to the first value set. Like the value sets of a
+// config, the first one starts with no values.
+func (s *ValueSetStream) rewind() error {
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.r = bufio.NewReader(s.f)
+	s.index = 0
+	s.current = make(map[string]MetricValue)
+	if s.format != ValueSourceCSV {
+		return nil
+	}
+	s.csv = csv.NewReader(s.r)
+	s.csv.ReuseRecord = true
+	header, err := s.csv.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%s has no header row", s.f.Name())
+	}
+	if err != nil {
+		return err
+	}
+	s.header = append([]string{}, header...)
+	return nil
+}
+
+func (s *ValueSetStream) read() (MetricValueSet, error) {
+	if s.format == ValueSourceCSV {
+		return s.readCSV()
+	}
+	return s.readJSON()
+}
+
+func (s *ValueSetStream) readJSON() (MetricValueSet, error) {
+	for {
+		line, err := s.r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			// JSON is YAML, and decoding it as YAML reads durations the
+			// same way as config files.
+			var result MetricValueSet
+			if err := yaml.UnmarshalStrict(line, &result); err != nil {
+				return MetricValueSet{}, err
+			}
+			return result, nil
+		}
+		if err != nil {
+			return MetricValueSet{}, err
+		}
+	}
+}
+
+func (s *ValueSetStream) readCSV() (MetricValueSet, error) {
+	record, err := s.csv.Read()
+	if err != nil {
+		return MetricValueSet{}, err
+	}
+	var result MetricValueSet
+	for i, cell := range record {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		name := s.header[i]
+		var parts []float64
+		for _, part := range strings.Split(cell, ";") {
+			x, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return MetricValueSet{}, fmt.Errorf(
+					"invalid value for '%s': %s", name, cell)
+			}
+			parts = append(parts, x)
+		}
+		value := MetricValue{Name: name}
+		if len(parts) == 1 {
+			value.Value = parts[0]
+		} else {
+			value.Observations = parts
+		}
+		result.ValueSet = append(result.ValueSet, value)
+	}
+	return result, nil
+}
+
+// Sample returns what the Engine plays back for v, drawing any random
+// observations and noise from this Engine.
+func (e *Engine) Sample(v MetricValue) Sample {
+	return e.sample(newEngineValue(v))
+}
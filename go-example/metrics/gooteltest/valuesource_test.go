@@ -0,0 +1,175 @@
+package gooteltest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openValueSource writes contents to a value source named name and opens
+// it for a gauge 'temp' and a histogram 'latency'.
+func openValueSource(t *testing.T, name, contents string) *ValueSetStream {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{
+		ValueSource: path,
+		Metrics: []MetricInfo{
+			{Name: "temp", Type: MetricTypeGauge},
+			{Name: "latency", Type: MetricTypeHistogram},
+		},
+	}
+	stream, err := config.OpenValueSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stream.Close() })
+	return stream
+}
+
+// nextValues returns the temp value and latency observations of the next
+// value set of stream.
+func nextValues(t *testing.T, stream *ValueSetStream) (float64, []float64) {
+	t.Helper()
+	values, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values["temp"].Value, values["latency"].Observations
+}
+
+const (
+	jsonlValueSource = `{"valueSet": [{"name": "temp", "value": 20}, {"name": "latency", "observations": [1, 2]}]}
+
+{"valueSet": [{"name": "temp", "value": 21}]}
+{"valueSet": [{"name": "latency", "observations": [3, 4]}]}
+`
+	csvValueSource = `temp,latency
+20,1;2
+21,
+,3;4
+`
+)
+
+func TestValueSetStream(t *testing.T) {
+	type values struct {
+		temp    float64
+		latency []float64
+	}
+	// Metrics missing from a value set keep their value, and the stream
+	// starts again with no values after the last value set.
+	want := []values{
+		{20, []float64{1, 2}},
+		{21, []float64{1, 2}},
+		{21, []float64{3, 4}},
+		{20, []float64{1, 2}},
+		{21, []float64{1, 2}},
+	}
+	for _, tc := range []struct {
+		name     string
+		contents string
+	}{
+		{name: "values.jsonl", contents: jsonlValueSource},
+		{name: "values.csv", contents: csvValueSource},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := openValueSource(t, tc.name, tc.contents)
+			for i, w := range want {
+				temp, latency := nextValues(t, stream)
+				if temp != w.temp || !reflect.DeepEqual(latency, w.latency) {
+					t.Errorf("Value set %d: got %v %v, want %v %v",
+						i, temp, latency, w.temp, w.latency)
+				}
+				if got := stream.Index(); got != i%3 {
+					t.Errorf("Value set %d: got index %d, want %d", i, got, i%3)
+				}
+			}
+		})
+	}
+}
+
+func TestValueSetStreamSeek(t *testing.T) {
+	stream := openValueSource(t, "values.jsonl", jsonlValueSource)
+	for _, tc := range []struct {
+		index   int
+		temp    float64
+		latency []float64
+	}{
+		{index: 2, temp: 21, latency: []float64{3, 4}},
+		{index: 0, temp: 20, latency: []float64{1, 2}},
+		{index: 4, temp: 21, latency: []float64{1, 2}},
+	} {
+		if err := stream.Seek(tc.index); err != nil {
+			t.Fatal(err)
+		}
+		temp, latency := nextValues(t, stream)
+		if temp != tc.temp || !reflect.DeepEqual(latency, tc.latency) {
+			t.Errorf("Seek(%d): got %v %v, want %v %v",
+				tc.index, temp, latency, tc.temp, tc.latency)
+		}
+		if got, want := stream.Position(), tc.index%3+1; got != want {
+			t.Errorf("Seek(%d): got position %d, want %d", tc.index, got, want)
+		}
+	}
+}
+
+func TestValueSetStreamBadRows(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "bad.jsonl",
+			contents: "{\"valueSet\": [{\"name\": \"temp\", \"value\": 20}]}\n{\"valueSet\": [\n",
+			want:     "value set 2:",
+		},
+		{
+			name:     "unknown.jsonl",
+			contents: `{"valueSet": [{"name": "pressure", "value": 20}]}`,
+			want:     "Unknown metric name 'pressure'",
+		},
+		{
+			name:     "bad.csv",
+			contents: "temp,latency\n20,1\nwarm,2\n",
+			want:     "value set 2: invalid value for 'temp': warm",
+		},
+		{
+			name:     "short.csv",
+			contents: "temp,latency\n20\n",
+			want:     "value set 1:",
+		},
+		{
+			name:     "empty.jsonl",
+			contents: "\n",
+			want:     "has no value sets",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := openValueSource(t, tc.name, tc.contents)
+			var err error
+			for i := 0; i < 3 && err == nil; i++ {
+				_, err = stream.Next()
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValueSourceWithoutHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.csv")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{ValueSource: path}
+	if _, err := config.OpenValueSource(); err == nil ||
+		!strings.Contains(err.Error(), "has no header row") {
+		t.Errorf("Got error %v, want no header row", err)
+	}
+}
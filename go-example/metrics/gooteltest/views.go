@@ -0,0 +1,233 @@
+package gooteltest
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	AggregationDrop                 = "drop"
+	AggregationSum                  = "sum"
+	AggregationLastValue            = "lastValue"
+	AggregationExplicitHistogram    = "explicitHistogram"
+	AggregationExponentialHistogram = "exponentialHistogram"
+)
+
+var aggregationTypes = map[string]bool{
+	AggregationDrop:                 true,
+	AggregationSum:                  true,
+	AggregationLastValue:            true,
+	AggregationExplicitHistogram:    true,
+	AggregationExponentialHistogram: true,
+}
+
+// Default limits of exponential histograms.
+const (
+	DefaultExponentialMaxSize  = 160
+	DefaultExponentialMaxScale = 20
+)
+
+// View changes how the SDK aggregates and exports the instruments it
+// matches, like the views of an OpenTelemetry SDK. Each instrument uses
+// the first view that matches it, if any.
+type View struct {
+
+	// The name of the instruments this view applies to, as they are sent
+	// with any prefix. '*' matches any run of characters and '?' matches
+	// one character.
+	Instrument string `yaml:"instrument"`
+
+	// The name to export matched instruments under. Only allowed when
+	// Instrument has no wildcards. Default is the instrument name.
+	Name string `yaml:"name,omitempty"`
+
+	// If not empty, only these attribute keys are kept.
+	AllowAttributes []string `yaml:"allowAttributes,omitempty"`
+
+	// Attribute keys to drop.
+	DenyAttributes []string `yaml:"denyAttributes,omitempty"`
+
+	// How matched instruments are aggregated. Default is the default
+	// aggregation of each instrument.
+	Aggregation *ViewAggregation `yaml:"aggregation,omitempty"`
+}
+
+// ViewAggregation is the aggregation of a View.
+type ViewAggregation struct {
+
+	// drop, sum, lastValue, explicitHistogram, or exponentialHistogram.
+	// drop sends nothing for matched instruments.
+	Type string `yaml:"type"`
+
+	// The bucket boundaries of an explicitHistogram. Default is the
+	// boundaries of the metric or DefaultBoundaries.
+	Boundaries []float64 `yaml:"boundaries,omitempty"`
+
+	// The maximum number of buckets of an exponentialHistogram for each
+	// of positive and negative values. Default is 160.
+	MaxSize int32 `yaml:"maxSize,omitempty"`
+
+	// The maximum scale of an exponentialHistogram, from -10 to 20.
+	// Default is 20.
+	MaxScale *int32 `yaml:"maxScale,omitempty"`
+}
+
+// Matches reports whether v applies to the instrument with the given name.
+func (v *View) Matches(instrument string) bool {
+	matched, _ := path.Match(v.Instrument, instrument)
+	return matched
+}
+
+// ExportedName returns the name under which v exports instrument.
+func (v *View) ExportedName(instrument string) string {
+	if v == nil || v.Name == "" {
+		return instrument
+	}
+	return v.Name
+}
+
+// FilterAttributes returns attrs with the attributes that v drops
+// removed. It returns attrs itself if v keeps all of them.
+func (v *View) FilterAttributes(attrs map[string]string) map[string]string {
+	if v == nil || (len(v.AllowAttributes) == 0 && len(v.DenyAttributes) == 0) {
+		return attrs
+	}
+	result := make(map[string]string, len(attrs))
+	for k, value := range attrs {
+		if v.KeepsAttribute(k) {
+			result[k] = value
+		}
+	}
+	return result
+}
+
+// KeepsAttribute reports whether v keeps the attribute with the given
+// key.
+func (v *View) KeepsAttribute(key string) bool {
+	if v == nil {
+		return true
+	}
+	for _, k := range v.DenyAttributes {
+		if k == key {
+			return false
+		}
+	}
+	if len(v.AllowAttributes) == 0 {
+		return true
+	}
+	for _, k := range v.AllowAttributes {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregationType returns the type of the aggregation of v or "" if v
+// leaves aggregation alone.
+func (v *View) AggregationType() string {
+	if v == nil || v.Aggregation == nil {
+		return ""
+	}
+	return v.Aggregation.Type
+}
+
+// ViewFor returns the first view of this config that matches the
+// instrument with the given name or nil if there is none.
+func (c *Config) ViewFor(instrument string) *View {
+	for i := range c.Views {
+		if c.Views[i].Matches(instrument) {
+			return &c.Views[i]
+		}
+	}
+	return nil
+}
+
+// ExportedName returns the name under which metric m is exported after
+// views.
+func (c *Config) ExportedName(m MetricInfo) string {
+	name := c.InstrumentName(m)
+	return c.ViewFor(name).ExportedName(name)
+}
+
+func (a *ViewAggregation) fixDefaults() {
+	if a.Type != AggregationExponentialHistogram {
+		return
+	}
+	if a.MaxSize == 0 {
+		a.MaxSize = DefaultExponentialMaxSize
+	}
+	if a.MaxScale == nil {
+		maxScale := int32(DefaultExponentialMaxScale)
+		a.MaxScale = &maxScale
+	}
+}
+
+func checkViews(config *Config) error {
+	for _, v := range config.Views {
+		if v.Instrument == "" {
+			return errors.New("views must have an instrument")
+		}
+		if _, err := path.Match(v.Instrument, ""); err != nil {
+			return fmt.Errorf("Invalid view instrument pattern: %s", v.Instrument)
+		}
+		if v.Name != "" && strings.ContainsAny(v.Instrument, "*?[") {
+			return fmt.Errorf(
+				"View of '%s' matches several instruments so cannot rename them",
+				v.Instrument)
+		}
+		a := v.Aggregation
+		if a == nil {
+			continue
+		}
+		if !aggregationTypes[a.Type] {
+			return fmt.Errorf("Unknown aggregation type: %s", a.Type)
+		}
+		if len(a.Boundaries) > 0 && a.Type != AggregationExplicitHistogram {
+			return fmt.Errorf(
+				"View of '%s' has boundaries but is not an explicitHistogram",
+				v.Instrument)
+		}
+		if err := checkBoundaries(MetricInfo{
+			Name:       v.Instrument,
+			Type:       MetricTypeHistogram,
+			Boundaries: a.Boundaries,
+		}); err != nil {
+			return err
+		}
+		if a.Type != AggregationExponentialHistogram {
+			if a.MaxSize != 0 || a.MaxScale != nil {
+				return fmt.Errorf(
+					"View of '%s' has maxSize or maxScale but is not an exponentialHistogram",
+					v.Instrument)
+			}
+			continue
+		}
+		if a.MaxSize < 2 {
+			return fmt.Errorf(
+				"View of '%s' needs a maxSize of at least 2", v.Instrument)
+		}
+		if *a.MaxScale < -10 || *a.MaxScale > 20 {
+			return fmt.Errorf(
+				"View of '%s' needs a maxScale from -10 to 20", v.Instrument)
+		}
+	}
+	return nil
+}
+
+// checkViewAggregation returns an error if the view of metric m has an
+// aggregation that the SDK does not allow for the instrument of m: sums
+// of gauges and last values of sums and histograms.
+func checkViewAggregation(config *Config, m MetricInfo) error {
+	view := config.ViewFor(config.InstrumentName(m))
+	switch t := view.AggregationType(); {
+	case t == AggregationSum && m.Type == MetricTypeGauge,
+		t == AggregationLastValue && m.Type != MetricTypeGauge:
+		return fmt.Errorf(
+			"View of '%s' cannot aggregate %s '%s' as %s",
+			view.Instrument, m.Type, m.Name, t)
+	}
+	return nil
+}
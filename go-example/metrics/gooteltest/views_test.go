@@ -0,0 +1,138 @@
+package gooteltest
+
+import (
+	"strings"
+	"testing"
+)
+
+const viewMetrics = `
+namePrefix: ""
+metrics:
+- name: "temperature"
+  type: "gauge"
+- name: "requests"
+  type: "sum"
+- name: "latency"
+  type: "histogram"
+`
+
+func TestReadConfigExponentialView(t *testing.T) {
+	config, err := ReadConfig(strings.NewReader(viewMetrics + `
+views:
+- instrument: "latency"
+  aggregation:
+    type: "exponentialHistogram"
+- instrument: "requests"
+  aggregation:
+    type: "exponentialHistogram"
+    maxSize: 20
+    maxScale: 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct {
+		maxSize, maxScale int32
+	}{
+		{DefaultExponentialMaxSize, DefaultExponentialMaxScale},
+		{20, 0},
+	} {
+		a := config.Views[i].Aggregation
+		if a.MaxSize != want.maxSize || *a.MaxScale != want.maxScale {
+			t.Errorf("View %d has maxSize %d and maxScale %d, want %d and %d",
+				i, a.MaxSize, *a.MaxScale, want.maxSize, want.maxScale)
+		}
+	}
+}
+
+func TestReadConfigRejectsViews(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		views string
+		want  string
+	}{
+		{
+			name: "sumOfGauge",
+			views: `
+- instrument: "temperature"
+  aggregation:
+    type: "sum"`,
+			want: "View of 'temperature' cannot aggregate gauge 'temperature' as sum",
+		},
+		{
+			name: "lastValueOfSum",
+			views: `
+- instrument: "req*"
+  aggregation:
+    type: "lastValue"`,
+			want: "View of 'req*' cannot aggregate sum 'requests' as lastValue",
+		},
+		{
+			name: "lastValueOfHistogram",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "lastValue"`,
+			want: "View of 'latency' cannot aggregate histogram 'latency' as lastValue",
+		},
+		{
+			name: "unknownType",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "summary"`,
+			want: "Unknown aggregation type: summary",
+		},
+		{
+			name: "maxScale",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "exponentialHistogram"
+    maxScale: 21`,
+			want: "View of 'latency' needs a maxScale from -10 to 20",
+		},
+		{
+			name: "maxSize",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "exponentialHistogram"
+    maxSize: 1`,
+			want: "View of 'latency' needs a maxSize of at least 2",
+		},
+		{
+			name: "maxSizeOfExplicit",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "explicitHistogram"
+    maxSize: 20`,
+			want: "View of 'latency' has maxSize or maxScale but is not an exponentialHistogram",
+		},
+		{
+			name: "boundariesOfExponential",
+			views: `
+- instrument: "latency"
+  aggregation:
+    type: "exponentialHistogram"
+    boundaries: [1, 2]`,
+			want: "View of 'latency' has boundaries but is not an explicitHistogram",
+		},
+		{
+			name: "renameWildcard",
+			views: `
+- instrument: "l*"
+  name: "duration"`,
+			want: "View of 'l*' matches several instruments so cannot rename them",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadConfig(
+				strings.NewReader(viewMetrics + "views:" + tc.views + "\n"))
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("Got error %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
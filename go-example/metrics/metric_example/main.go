@@ -8,13 +8,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/metric"
-	"go.opentelemetry.io/otel/metric/global"
-	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
-	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
-	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"google.golang.org/grpc"
@@ -55,30 +53,24 @@ func initMetric() func() {
 		log.Fatal("failed to create resource: ", err)
 	}
 
-	// Create a contoller with the exporter which will be the global meter
-	// provider.
-	cont := controller.New(
-		processor.NewFactory(
-			simple.NewWithInexpensiveDistribution(),
+	// Create a meter provider with a reader that periodically collects
+	// metrics and sends them with the exporter.
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(
 			metricExporter,
-		),
-		controller.WithExporter(metricExporter),
-		controller.WithCollectPeriod(5*time.Second), // Update every 5 seconds
-		controller.WithResource(res),
+			sdkmetric.WithInterval(5*time.Second), // Update every 5 seconds
+		)),
+		sdkmetric.WithResource(res),
 	)
 
-	// Start the controller
-	if err := cont.Start(context.Background()); err != nil {
-		log.Fatal("failed to start controller: ", err)
-	}
-
-	// Register controller as global meter provider.
-	global.SetMeterProvider(cont)
+	// Register the meter provider as the global meter provider.
+	otel.SetMeterProvider(provider)
 
-	// Our quit function that we return will stop the controller and cancel
-	// the context on the exporter.
+	// Our quit function that we return will shut down the meter provider,
+	// which sends any remaining metrics, and cancel the context on the
+	// exporter.
 	return func() {
-		_ = cont.Stop(context.Background())
+		_ = provider.Shutdown(context.Background())
 		cancel()
 	}
 }
@@ -99,35 +91,45 @@ func main() {
 		c.String(200, "Hello, World!")
 	})
 
-	meter := global.Meter("example-meter")
+	meter := otel.Meter("example-meter")
 
 	// Register a gauge metric that reports a random number between 0 and 1.
-	metric.Must(meter).NewFloat64GaugeObserver(
+	_, err := meter.Float64ObservableGauge(
 
 		// Name of gauge metric
 		"random-gauge-metric",
 
 		// Callback function that gets called every 5 seconds (or whaatever the
 		// collect period is set to) to update the metric.
-		func(_ context.Context, result metric.Float64ObserverResult) {
+		metric.WithFloat64Callback(
+			func(_ context.Context, result metric.Float64Observer) error {
 
-			// Report the random number
-			result.Observe(rand.Float64())
-		},
+				// Report the random number
+				result.Observe(rand.Float64())
+				return nil
+			}),
 	)
+	if err != nil {
+		log.Fatal("failed to register gauge: ", err)
+	}
 
 	// Register a sum metric that reports how many requests were made to this
 	// server.
-	metric.Must(meter).NewFloat64CounterObserver(
+	_, err = meter.Float64ObservableCounter(
 
 		// Name of counter metric
 		"request-count",
 
 		// Callback to update the metric
-		func(_ context.Context, result metric.Float64ObserverResult) {
-			result.Observe(float64(atomic.LoadInt64(&requestCount)))
-		},
+		metric.WithFloat64Callback(
+			func(_ context.Context, result metric.Float64Observer) error {
+				result.Observe(float64(atomic.LoadInt64(&requestCount)))
+				return nil
+			}),
 	)
+	if err != nil {
+		log.Fatal("failed to register counter: ", err)
+	}
 
 	// Run the server
 	r.Run(":8080")